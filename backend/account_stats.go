@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// pendingStats holds the stats of the signing decisions that are not flushed to the storage yet, by account.
+// The accounts of a stripe are guarded by the mutex of the stripe, so counting a decision
+// doesn't touch the storage and decisions about other accounts rarely wait.
+// Stats of the decisions since the last flush are lost if the plugin stops.
+type pendingStats struct {
+	locks    stripedMutex
+	accounts [stripedMutexSize]map[string]*store.AccountStats
+}
+
+// newPendingStats is the constructor of pendingStats.
+func newPendingStats() *pendingStats {
+	ret := &pendingStats{}
+	for i := range ret.accounts {
+		ret.accounts[i] = make(map[string]*store.AccountStats)
+	}
+	return ret
+}
+
+// add counts the signing decision of the given audit record in the stats of its account.
+func (p *pendingStats) add(record *store.AuditRecord) {
+	if record.Outcome == store.AuditOutcomeNotFound || len(record.PublicKey) == 0 {
+		return
+	}
+
+	publicKey := strings.ToLower(record.PublicKey)
+	lock := p.locks.get(publicKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	accounts := p.accounts[stripeOf(publicKey)]
+	stats, ok := accounts[publicKey]
+	if !ok {
+		stats = store.NewAccountStats()
+		accounts[publicKey] = stats
+	}
+	stats.Add(record)
+}
+
+// open returns the stored stats of the account with the given public key, with its pending stats added.
+func (p *pendingStats) open(vaultStore *store.HashicorpVaultStore, publicKey string) (*store.AccountStats, error) {
+	lock := p.locks.get(publicKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	stats, err := vaultStore.OpenAccountStats(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending, ok := p.accounts[stripeOf(publicKey)][publicKey]; ok {
+		stats.Merge(pending)
+	}
+	return stats, nil
+}
+
+// list returns the stored stats of all accounts by public key, with their pending stats added.
+func (p *pendingStats) list(vaultStore *store.HashicorpVaultStore) (map[string]*store.AccountStats, error) {
+	p.locks.lockAll()
+	defer p.locks.unlockAll()
+
+	ret, err := vaultStore.ListAccountStats()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, accounts := range p.accounts {
+		for publicKey, pending := range accounts {
+			stats, ok := ret[publicKey]
+			if !ok {
+				stats = store.NewAccountStats()
+				ret[publicKey] = stats
+			}
+			stats.Merge(pending)
+		}
+	}
+	return ret, nil
+}
+
+// flush adds the pending stats to the stored stats of their accounts.
+// Stats that failed to be stored are kept for the next flush.
+func (p *pendingStats) flush(vaultStore *store.HashicorpVaultStore) error {
+	for i := range p.accounts {
+		if err := p.flushStripe(vaultStore, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushStripe adds the pending stats of the accounts of the given stripe to their stored stats.
+func (p *pendingStats) flushStripe(vaultStore *store.HashicorpVaultStore, stripe int) error {
+	p.locks.stripes[stripe].Lock()
+	defer p.locks.stripes[stripe].Unlock()
+
+	accounts := p.accounts[stripe]
+	for publicKey, stats := range accounts {
+		if err := vaultStore.AddAccountStats(publicKey, stats); err != nil {
+			return err
+		}
+		delete(accounts, publicKey)
+	}
+	return nil
+}
+
+// reset deletes the stored and the pending stats of the account with the given public key.
+func (p *pendingStats) reset(vaultStore *store.HashicorpVaultStore, publicKey string) error {
+	lock := p.locks.get(publicKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := vaultStore.ResetAccountStats(publicKey); err != nil {
+		return err
+	}
+
+	delete(p.accounts[stripeOf(publicKey)], publicKey)
+	return nil
+}
+
+// resetAll deletes the stored and the pending stats of all accounts.
+// It returns the number of reset accounts.
+func (p *pendingStats) resetAll(vaultStore *store.HashicorpVaultStore) (int, error) {
+	p.locks.lockAll()
+	defer p.locks.unlockAll()
+
+	publicKeys, err := vaultStore.ResetAllAccountStats()
+	if err != nil {
+		return 0, err
+	}
+
+	reset := make(map[string]bool, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		reset[publicKey] = true
+	}
+	for i, accounts := range p.accounts {
+		for publicKey := range accounts {
+			reset[publicKey] = true
+		}
+		p.accounts[i] = make(map[string]*store.AccountStats)
+	}
+	return len(reset), nil
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"encoding/hex"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// accountWallet is a wallet of the single account a request signs with.
+// The sign paths look the account up through the account index, the signer gets it from this wallet
+// instead of opening the wallet and looking the account up again.
+type accountWallet struct {
+	account core.ValidatorAccount
+}
+
+// newAccountWallet is the constructor of accountWallet.
+func newAccountWallet(account core.ValidatorAccount) *accountWallet {
+	return &accountWallet{
+		account: account,
+	}
+}
+
+// ID implements core.Wallet, the wallet has no ID of its own.
+func (wallet *accountWallet) ID() uuid.UUID {
+	return uuid.Nil
+}
+
+// Type implements core.Wallet.
+func (wallet *accountWallet) Type() core.WalletType {
+	return core.ND
+}
+
+// CreateValidatorAccount implements core.Wallet, accounts can't be created.
+func (wallet *accountWallet) CreateValidatorAccount(seed []byte, indexPointer *int) (core.ValidatorAccount, error) {
+	return nil, errors.New("accounts can't be created in a signing wallet")
+}
+
+// Accounts implements core.Wallet.
+func (wallet *accountWallet) Accounts() []core.ValidatorAccount {
+	return []core.ValidatorAccount{wallet.account}
+}
+
+// AccountByID implements core.Wallet.
+func (wallet *accountWallet) AccountByID(id uuid.UUID) (core.ValidatorAccount, error) {
+	if wallet.account.ID() != id {
+		return nil, wallet_hd.ErrAccountNotFound
+	}
+	return wallet.account, nil
+}
+
+// AccountByPublicKey implements core.Wallet.
+func (wallet *accountWallet) AccountByPublicKey(pubKey string) (core.ValidatorAccount, error) {
+	if hex.EncodeToString(wallet.account.ValidatorPublicKey().Marshal()) != pubKey {
+		return nil, wallet_hd.ErrAccountNotFound
+	}
+	return wallet.account, nil
+}
+
+// DeleteAccountByPublicKey implements core.Wallet, accounts can't be deleted.
+func (wallet *accountWallet) DeleteAccountByPublicKey(pubKey string) error {
+	return errors.New("accounts can't be deleted from a signing wallet")
+}
+
+// SetContext implements core.Wallet, the wallet has no storage.
+func (wallet *accountWallet) SetContext(ctx *core.WalletContext) {}
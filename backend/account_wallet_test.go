@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountWallet(t *testing.T) {
+	storage, err := baseHashicorpStorage(&logical.InmemStorage{}, context.Background())
+	require.NoError(t, err)
+
+	publicKey := basicAttestationData()["public_key"].(string)
+	account, err := storage.AccountByPublicKey(publicKey)
+	require.NoError(t, err)
+
+	wallet := newAccountWallet(account)
+
+	res, err := wallet.AccountByPublicKey(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, account.ID(), res.ID())
+
+	res, err = wallet.AccountByID(account.ID())
+	require.NoError(t, err)
+	require.Equal(t, account.ID(), res.ID())
+
+	_, err = wallet.AccountByPublicKey("b8e9d3b4e1f3c8ab5b3ae5e5b2a1f0d2a8d6a55d8c0bfe9e1c3c1e1c1ab9c2a7e77a2c9b2d0f9f1d1a8c2a4e6c7d2e1f")
+	require.Equal(t, wallet_hd.ErrAccountNotFound, err)
+
+	_, err = wallet.AccountByID(uuid.New())
+	require.Equal(t, wallet_hd.ErrAccountNotFound, err)
+}
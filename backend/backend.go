@@ -3,12 +3,15 @@ package backend
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/pkg/errors"
 
+	"github.com/bloxapp/key-vault/backend/store"
 	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
@@ -26,7 +29,12 @@ func Factory(version string) logical.Factory {
 // newBackend returns the backend
 func newBackend(version string) *backend {
 	b := &backend{
-		Version: version,
+		Version:  version,
+		cache:    store.NewCache(),
+		clock:    time.Now,
+		metrics:  newBackendMetrics(),
+		webhooks: newWebhookDispatcher(),
+		stats:    newPendingStats(),
 	}
 	b.Backend = &framework.Backend{
 		Help: "",
@@ -34,17 +42,36 @@ func newBackend(version string) *backend {
 			versionPaths(b),
 			storagePaths(b),
 			storageSlashingPaths(b),
+			storageMigrationPaths(b),
+			storageAccountIndexPaths(b),
+			backupPaths(b),
 			accountsPaths(b),
+			accountMetadataPaths(b),
+			walletPaths(b),
+			depositDataPaths(b),
+			blsToExecutionChangePaths(b),
 			signsPaths(b),
 			configPaths(b),
+			configHistoryPaths(b),
+			auditPaths(b),
+			metricsPaths(b),
+			healthPaths(b),
+			statsPaths(b),
+			webhooksPaths(b),
 		),
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
 				"wallet/",
+				"encryption/",
+				"webhooks/",
 			},
 		},
-		Secrets:     []*framework.Secret{},
-		BackendType: logical.TypeLogical,
+		Secrets:        []*framework.Secret{},
+		BackendType:    logical.TypeLogical,
+		InitializeFunc: b.initialize,
+		Invalidate:     b.invalidate,
+		PeriodicFunc:   b.periodic,
+		Clean:          b.cleanup,
 	}
 
 	return b
@@ -54,12 +81,136 @@ func newBackend(version string) *backend {
 type backend struct {
 	*framework.Backend
 	Version string
+
+	// cache holds the decoded config, wallet and accounts of the mount
+	cache *store.Cache
+
+	// clock returns the wall-clock time the signed slots are checked against
+	clock func() time.Time
+
+	// metrics holds the signing and storage metrics of the mount
+	metrics *backendMetrics
+
+	// pluginLogLevel is the log level the plugin was started with
+	pluginLogLevel     hclog.Level
+	pluginLogLevelOnce sync.Once
+
+	// walletLock serializes the changes of the wallet and its accounts. Storage writes
+	// only run on the active node, so a mutex is enough to keep them from racing.
+	walletLock sync.Mutex
+
+	// signLock is held for reading while slashing history is written, and for writing by
+	// backups and restores, so they export and replace a mount that doesn't change meanwhile.
+	// It is taken after walletLock.
+	signLock sync.RWMutex
+
+	// stats counts the signing decisions of the accounts until they are flushed by periodic
+	stats *pendingStats
+
+	// webhooks sends the events of the mount to its webhook targets
+	webhooks *webhookDispatcher
+}
+
+// HandleRequest handles the request with its storage operations timed.
+// Errors with a code are returned as responses with the HTTP status of the code.
+func (b *backend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Storage != nil {
+		req.Storage = &metricsStorage{Storage: req.Storage, metrics: b.metrics}
+	}
+
+	res, err := b.Backend.HandleRequest(ctx, req)
+	if err != nil && len(errorex.CodeOf(err)) > 0 {
+		return b.prepareErrorResponse(err)
+	}
+
+	return res, err
+}
+
+// periodic stores the account stats and prunes the audit records of the mount.
+func (b *backend) periodic(ctx context.Context, req *logical.Request) error {
+	if err := b.flushAccountStats(ctx, req); err != nil {
+		return err
+	}
+
+	return b.pruneAuditRecords(ctx, req)
+}
+
+// cleanup stops the background work of the backend.
+func (b *backend) cleanup(ctx context.Context) {
+	b.webhooks.stop()
+}
+
+// initialize applies the log level of the mount and upgrades the storage to the current schema version.
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	config, err := storedConfig(ctx, req.Storage)
+	if err != nil {
+		return errors.Wrap(err, "failed to get config")
+	}
+	if config != nil {
+		b.setLogLevel(config)
+	}
+
+	if _, err := b.migrate(ctx, req.Storage); err != nil {
+		return errors.Wrap(err, "failed to migrate storage")
+	}
+
+	return nil
+}
+
+// migrate runs the pending storage migrations and logs their progress.
+func (b *backend) migrate(ctx context.Context, storage logical.Storage) ([]store.Migration, error) {
+	vaultStore := store.NewHashicorpVaultStore(ctx, storage, "")
+	vaultStore.SetLogger(b.Logger())
+	if err := vaultStore.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	return vaultStore.Migrate(func(migration store.Migration) {
+		b.Logger().Info("Migrating storage", "schema_version", migration.Version, "description", migration.Description)
+	})
+}
+
+// invalidate drops the cached records of a storage key changed by another node.
+func (b *backend) invalidate(ctx context.Context, key string) {
+	b.cache.Invalidate(key)
+}
+
+// cachedStore returns the config and the store of the request with the wallet
+// and its accounts read through the backend cache. The store must not be used to
+// change the wallet.
+func (b *backend) cachedStore(ctx context.Context, req *logical.Request) (*Config, *store.HashicorpVaultStore, error) {
+	generation, err := b.cache.Sync(ctx, req.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var config *Config
+	if cached, ok := b.cache.Get(generation, "config"); ok {
+		config = cached.(*Config)
+	} else {
+		if config, err = b.configured(ctx, req); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get config")
+		}
+		b.cache.Put(generation, "config", config)
+
+		// The config may have been changed by another node
+		b.setLogLevel(config)
+	}
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	storage.SetCache(b.cache, generation)
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	return config, storage, nil
 }
 
 func (b *backend) pathExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
 	out, err := req.Storage.Get(ctx, req.Path)
 	if err != nil {
-		b.Logger().Error("Path existence check failed", err)
+		b.requestLogger(req).Error("Path existence check failed", "error", err)
 		return false, fmt.Errorf("existence check failed: %v", err)
 	}
 
@@ -67,18 +218,16 @@ func (b *backend) pathExistenceCheck(ctx context.Context, req *logical.Request,
 }
 
 func (b *backend) notFoundResponse() (*logical.Response, error) {
-	return logical.RespondWithStatusCode(&logical.Response{
-		Data: map[string]interface{}{
-			"message":     "account not found",
-			"status_code": http.StatusNotFound,
-		},
-	}, nil, http.StatusNotFound)
+	return errorex.NewCodedError(errorex.CodeAccountNotFound, "account not found").ToLogicalResponse()
 }
 
 func (b *backend) prepareErrorResponse(originError error) (*logical.Response, error) {
 	switch err := errors.Cause(originError).(type) {
 	case *errorex.ErrBadRequest:
 		return err.ToLogicalResponse()
+	case *errorex.CodedError:
+		// The message keeps the context of the wrapped error
+		return errorex.NewCodedError(err.Code, originError.Error()).ToLogicalResponse()
 	case nil:
 		return nil, nil
 	default:
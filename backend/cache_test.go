@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/stores/in_memory"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+const cachedPublicKey = "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+
+func newSignAggregationRequest(storage logical.Storage) *logical.Request {
+	return &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts/sign-aggregation",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"public_key": cachedPublicKey,
+			"dataToSign": "01020304",
+		},
+	}
+}
+
+func TestSigningCache(t *testing.T) {
+	lb, _ := getBackend(t)
+	b := lb.(*backend)
+
+	req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-aggregation")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	t.Run("signing fills the cache", func(t *testing.T) {
+		_, err := b.HandleRequest(context.Background(), newSignAggregationRequest(req.Storage))
+		require.NoError(t, err)
+		require.NotZero(t, b.cache.Len())
+	})
+
+	t.Run("wallet changes are visible", func(t *testing.T) {
+		// replace the wallet with one without accounts
+		inMemStore := in_memory.NewInMemStore(core.MainNetwork)
+		require.NoError(t, inMemStore.SaveWallet(wallet_hd.NewHDWallet(&core.WalletContext{Storage: inMemStore})))
+		_, err := store.FromInMemoryStore(context.Background(), inMemStore, req.Storage)
+		require.NoError(t, err)
+
+		res, err := b.HandleRequest(context.Background(), newSignAggregationRequest(req.Storage))
+		require.NoError(t, err)
+		require.EqualValues(t, 404, res.Data["http_status_code"], res.Data)
+
+		// restore the account
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		_, err = b.HandleRequest(context.Background(), newSignAggregationRequest(req.Storage))
+		require.NoError(t, err)
+	})
+
+	t.Run("config changes are visible", func(t *testing.T) {
+		configReq := logical.TestRequest(t, logical.CreateOperation, "config")
+		configReq.Storage = req.Storage
+		configReq.Data = map[string]interface{}{
+			"network":     string(core.MainNetwork),
+			"slot_window": 8,
+		}
+		_, err := b.HandleRequest(context.Background(), configReq)
+		require.NoError(t, err)
+
+		config, _, err := b.cachedStore(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 8, config.SlotWindow)
+	})
+
+	t.Run("invalidate drops the cache", func(t *testing.T) {
+		_, err := b.HandleRequest(context.Background(), newSignAggregationRequest(req.Storage))
+		require.NoError(t, err)
+		require.NotZero(t, b.cache.Len())
+
+		b.invalidate(context.Background(), store.GenerationPath)
+		require.Zero(t, b.cache.Len())
+	})
+}
+
+func BenchmarkSignAggregation(b *testing.B) {
+	run := func(b *testing.B, cached bool) {
+		lb, _ := getBackend(b)
+		backend := lb.(*backend)
+
+		req := newSignAggregationRequest(&logical.InmemStorage{})
+		setupBaseStorage(b, req)
+		require.NoError(b, setupStorageWithWalletAndAccounts(req.Storage))
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !cached {
+				backend.cache.Purge()
+			}
+
+			if _, err := backend.HandleRequest(context.Background(), newSignAggregationRequest(req.Storage)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		run(b, true)
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		run(b, false)
+	})
+}
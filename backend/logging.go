@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// requestLogger returns the logger of the given request.
+// Key material is never logged, accounts are logged by public key.
+func (b *backend) requestLogger(req *logical.Request) hclog.Logger {
+	return b.Logger().With("request_id", req.ID, "path", req.Path)
+}
+
+// setLogLevel applies the log level of the mount config.
+// The level the plugin was started with is restored if the config has none.
+func (b *backend) setLogLevel(config *Config) {
+	b.pluginLogLevelOnce.Do(func() {
+		b.pluginLogLevel = loggerLevel(b.Logger())
+	})
+
+	level := hclog.LevelFromString(config.LogLevel)
+	if level == hclog.NoLevel {
+		level = b.pluginLogLevel
+	}
+	b.Logger().SetLevel(level)
+}
+
+// loggerLevel returns the level of the given logger.
+func loggerLevel(logger hclog.Logger) hclog.Level {
+	switch {
+	case logger.IsTrace():
+		return hclog.Trace
+	case logger.IsDebug():
+		return hclog.Debug
+	case logger.IsInfo():
+		return hclog.Info
+	case logger.IsWarn():
+		return hclog.Warn
+	default:
+		return hclog.Error
+	}
+}
+
+// logSignDecision logs the decision of a sign request.
+func (b *backend) logSignDecision(req *logical.Request, record *store.AuditRecord) {
+	args := []interface{}{
+		"operation", record.MessageType,
+		"public_key", record.PublicKey,
+		"slot", record.Slot,
+		"source_epoch", record.SourceEpoch,
+		"target_epoch", record.TargetEpoch,
+		"outcome", record.Outcome,
+	}
+	if len(record.Reason) > 0 {
+		args = append(args, "reason", record.Reason)
+	}
+
+	logger := b.requestLogger(req)
+	switch record.Outcome {
+	case store.AuditOutcomeSigned:
+		logger.Debug("Signed message", args...)
+	case store.AuditOutcomeFailed:
+		logger.Error("Failed to sign message", args...)
+	default:
+		logger.Warn("Refused to sign message", args...)
+	}
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes the metric names, it is the name the plugin is registered with.
+const metricsNamespace = "ethsign"
+
+// backendMetrics holds the signing and storage metrics of a mount.
+// Metrics are kept in memory, every Vault node reports its own.
+type backendMetrics struct {
+	registry        *prometheus.Registry
+	signRequests    *prometheus.CounterVec
+	signDuration    *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+}
+
+// newBackendMetrics is the constructor of backendMetrics.
+func newBackendMetrics() *backendMetrics {
+	m := &backendMetrics{
+		registry: prometheus.NewRegistry(),
+		signRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sign_requests_total",
+			Help:      "Number of signing requests by message type and outcome.",
+		}, []string{"operation", "outcome"}),
+		signDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "sign_duration_seconds",
+			Help:      "Latency of signing requests by message type and outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"operation", "outcome"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Latency of storage operations.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 14),
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(m.signRequests, m.signDuration, m.storageDuration)
+
+	return m
+}
+
+// observeSign records a signing request started at the given time.
+func (m *backendMetrics) observeSign(messageType string, outcome string, start time.Time) {
+	m.signRequests.WithLabelValues(messageType, outcome).Inc()
+	m.signDuration.WithLabelValues(messageType, outcome).Observe(time.Since(start).Seconds())
+
+	// Telemetry of Vault
+	labels := []metrics.Label{{Name: "operation", Value: messageType}, {Name: "outcome", Value: outcome}}
+	metrics.IncrCounterWithLabels([]string{metricsNamespace, "sign", "requests"}, 1, labels)
+	metrics.MeasureSinceWithLabels([]string{metricsNamespace, "sign", "duration"}, start, labels)
+}
+
+// observeStorage records a storage operation started at the given time.
+func (m *backendMetrics) observeStorage(operation string, start time.Time) {
+	m.storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	metrics.MeasureSinceWithLabels([]string{metricsNamespace, "storage", "duration"}, start, []metrics.Label{{Name: "operation", Value: operation}})
+}
+
+// metricsStorage times the operations of the wrapped storage.
+type metricsStorage struct {
+	logical.Storage
+	metrics *backendMetrics
+}
+
+// List implements logical.Storage
+func (s *metricsStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	defer s.metrics.observeStorage("list", time.Now())
+	return s.Storage.List(ctx, prefix)
+}
+
+// Get implements logical.Storage
+func (s *metricsStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	defer s.metrics.observeStorage("get", time.Now())
+	return s.Storage.Get(ctx, key)
+}
+
+// Put implements logical.Storage
+func (s *metricsStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	defer s.metrics.observeStorage("put", time.Now())
+	return s.Storage.Put(ctx, entry)
+}
+
+// Delete implements logical.Storage
+func (s *metricsStorage) Delete(ctx context.Context, key string) error {
+	defer s.metrics.observeStorage("delete", time.Now())
+	return s.Storage.Delete(ctx, key)
+}
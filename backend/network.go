@@ -0,0 +1,227 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// Networks, next to the test, launchtest and main networks of the key manager
+const (
+	// MainnetNetwork is the Ethereum mainnet
+	MainnetNetwork core.Network = "mainnet"
+
+	// PraterNetwork is the Prater (Goerli) public testnet
+	PraterNetwork core.Network = "prater"
+
+	// GoerliNetwork is an alias of the Prater network
+	GoerliNetwork core.Network = "goerli"
+
+	// SepoliaNetwork is the Sepolia public testnet
+	SepoliaNetwork core.Network = "sepolia"
+
+	// HoleskyNetwork is the Holesky public testnet
+	HoleskyNetwork core.Network = "holesky"
+
+	// CustomNetwork is a network defined by the mount config
+	CustomNetwork core.Network = "custom"
+)
+
+// Fork is a scheduled fork of a network.
+type Fork struct {
+	Epoch   uint64 `json:"epoch"`
+	Version []byte `json:"version"`
+}
+
+// networkSpec holds the parameters of a known network.
+// The test, launchtest and main networks are only known by their fork version.
+type networkSpec struct {
+	genesisForkVersion    []byte
+	genesisValidatorsRoot []byte
+	genesisTime           uint64
+	forkSchedule          []Fork
+}
+
+var networkSpecs = map[core.Network]*networkSpec{
+	core.TestNetwork: {
+		genesisForkVersion: core.TestNetwork.ForkVersion(),
+	},
+	core.LaunchTestNetwork: {
+		genesisForkVersion: core.LaunchTestNetwork.ForkVersion(),
+	},
+	core.MainNetwork: {
+		genesisForkVersion: core.MainNetwork.ForkVersion(),
+	},
+	MainnetNetwork: {
+		genesisForkVersion:    []byte{0x00, 0x00, 0x00, 0x00},
+		genesisValidatorsRoot: mustDecodeHex("4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		genesisTime:           1606824023,
+		forkSchedule: []Fork{
+			{Epoch: 74240, Version: []byte{0x01, 0x00, 0x00, 0x00}},
+			{Epoch: 144896, Version: []byte{0x02, 0x00, 0x00, 0x00}},
+			{Epoch: 194048, Version: []byte{0x03, 0x00, 0x00, 0x00}},
+			{Epoch: 269568, Version: []byte{0x04, 0x00, 0x00, 0x00}},
+		},
+	},
+	PraterNetwork: {
+		genesisForkVersion:    []byte{0x00, 0x00, 0x10, 0x20},
+		genesisValidatorsRoot: mustDecodeHex("043db0d9a83813551ee2f33450d23797757d430911a9320530ad8a0eabc43efb"),
+		genesisTime:           1616508000,
+		forkSchedule: []Fork{
+			{Epoch: 36660, Version: []byte{0x01, 0x00, 0x10, 0x20}},
+			{Epoch: 112260, Version: []byte{0x02, 0x00, 0x10, 0x20}},
+			{Epoch: 162304, Version: []byte{0x03, 0x00, 0x10, 0x20}},
+			{Epoch: 231680, Version: []byte{0x04, 0x00, 0x10, 0x20}},
+		},
+	},
+	SepoliaNetwork: {
+		genesisForkVersion:    []byte{0x90, 0x00, 0x00, 0x69},
+		genesisValidatorsRoot: mustDecodeHex("d8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		genesisTime:           1655733600,
+		forkSchedule: []Fork{
+			{Epoch: 50, Version: []byte{0x90, 0x00, 0x00, 0x70}},
+			{Epoch: 100, Version: []byte{0x90, 0x00, 0x00, 0x71}},
+			{Epoch: 56832, Version: []byte{0x90, 0x00, 0x00, 0x72}},
+			{Epoch: 132608, Version: []byte{0x90, 0x00, 0x00, 0x73}},
+		},
+	},
+	HoleskyNetwork: {
+		genesisForkVersion:    []byte{0x01, 0x01, 0x70, 0x00},
+		genesisValidatorsRoot: mustDecodeHex("9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1"),
+		genesisTime:           1695902400,
+		forkSchedule: []Fork{
+			{Epoch: 0, Version: []byte{0x02, 0x01, 0x70, 0x00}},
+			{Epoch: 0, Version: []byte{0x03, 0x01, 0x70, 0x00}},
+			{Epoch: 256, Version: []byte{0x04, 0x01, 0x70, 0x00}},
+			{Epoch: 29696, Version: []byte{0x05, 0x01, 0x70, 0x00}},
+		},
+	},
+}
+
+// launchpadNames maps genesis fork versions to the network names of the staking launchpad and the deposit CLI.
+// The launchpad checks the name against the fork version of the deposit data, so the test, launchtest
+// and main networks of the key manager are named after the testnets sharing their fork version.
+var launchpadNames = map[string]string{
+	"00000000": "mainnet",
+	"00001020": "prater",
+	"90000069": "sepolia",
+	"01017000": "holesky",
+	"00000001": "medalla",
+	"00000002": "spadina",
+	"00000003": "zinken",
+}
+
+// keyManagerNetwork returns true for the networks known by the key manager library.
+// It panics on the fork version of other networks, which must be looked up with genesisForkVersion.
+func keyManagerNetwork(network core.Network) bool {
+	switch network {
+	case core.TestNetwork, core.LaunchTestNetwork, core.MainNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// knownNetwork returns the parameters of the given network, nil for custom and unknown networks.
+// Goerli is resolved to Prater.
+func knownNetwork(network core.Network) *networkSpec {
+	if network == GoerliNetwork {
+		network = PraterNetwork
+	}
+	return networkSpecs[network]
+}
+
+// networkNames returns the names accepted by the config endpoint.
+func networkNames() []interface{} {
+	names := make([]string, 0, len(networkSpecs)+2)
+	for network := range networkSpecs {
+		names = append(names, string(network))
+	}
+	names = append(names, string(GoerliNetwork), string(CustomNetwork))
+	sort.Strings(names)
+
+	ret := make([]interface{}, len(names))
+	for i, name := range names {
+		ret[i] = name
+	}
+	return ret
+}
+
+// genesisForkVersion returns the genesis fork version of the configured network.
+// Configs written before the fork version was stored fall back to the known network.
+func (config *Config) genesisForkVersion() []byte {
+	if len(config.GenesisForkVersion) > 0 {
+		return config.GenesisForkVersion
+	}
+
+	if spec := knownNetwork(config.Network); spec != nil {
+		return spec.genesisForkVersion
+	}
+	return nil
+}
+
+// launchpadNetworkName returns the launchpad name of the configured network.
+// Custom networks unknown to the launchpad keep their configured name.
+func (config *Config) launchpadNetworkName() string {
+	if name, ok := launchpadNames[hex.EncodeToString(config.genesisForkVersion())]; ok {
+		return name
+	}
+	return string(config.Network)
+}
+
+// ForkVersion returns the fork version active at the given epoch.
+func (config *Config) ForkVersion(epoch uint64) []byte {
+	version := config.genesisForkVersion()
+	for _, fork := range config.ForkSchedule {
+		if fork.Epoch <= epoch {
+			version = fork.Version
+		}
+	}
+	return version
+}
+
+// ValidDomain returns true if the given signature domain was computed for the configured network,
+// at any of its fork versions. Domains can't be checked without genesis validators root.
+func (config *Config) ValidDomain(domain []byte) bool {
+	if len(config.GenesisValidatorsRoot) == 0 {
+		return true
+	}
+
+	if len(domain) != 32 {
+		return false
+	}
+
+	var domainType e2types.DomainType
+	copy(domainType[:], domain[:4])
+
+	versions := [][]byte{config.genesisForkVersion()}
+	for _, fork := range config.ForkSchedule {
+		versions = append(versions, fork.Version)
+	}
+
+	for _, version := range versions {
+		if bytes.Equal(e2types.Domain(domainType, version, config.GenesisValidatorsRoot), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortForkSchedule orders the fork schedule by epoch.
+func sortForkSchedule(forks []Fork) {
+	sort.SliceStable(forks, func(i, j int) bool {
+		return forks[i].Epoch < forks[j].Epoch
+	})
+}
+
+// mustDecodeHex decodes the given HEX string and panics on failure.
+func mustDecodeHex(s string) []byte {
+	ret, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+func TestNetworks(t *testing.T) {
+	b, _ := getBackend(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		forkVersion string
+	}{
+		{
+			name:        "sepolia",
+			config:      map[string]interface{}{"network": "sepolia"},
+			forkVersion: "90000069",
+		},
+		{
+			name:        "holesky",
+			config:      map[string]interface{}{"network": "holesky"},
+			forkVersion: "01017000",
+		},
+		{
+			name: "custom",
+			config: map[string]interface{}{
+				"network":                 "custom",
+				"genesis_fork_version":    "10000038",
+				"genesis_validators_root": "83431ec7fcf92cfc44947fc0418e831c25e1d0806590231c439830db7ad54fda",
+				"genesis_time":            1700000000,
+			},
+			forkVersion: "10000038",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := logical.TestRequest(t, logical.UpdateOperation, "config")
+			req.Data = test.config
+			res, err := b.HandleRequest(ctx, req)
+			require.NoError(t, err)
+			require.Equal(t, test.forkVersion, res.Data["genesis_fork_version"])
+
+			do := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+				r := logical.TestRequest(t, operation, path)
+				r.Storage = req.Storage
+				r.Data = data
+				res, err := b.HandleRequest(ctx, r)
+				require.NoError(t, err)
+				return res
+			}
+
+			// the wallet and accounts are created with the fork version of the config
+			do(logical.CreateOperation, "wallet", nil)
+			res = do(logical.CreateOperation, "wallet/accounts", nil)
+			publicKey := res.Data["validationPubKey"].(string)
+
+			res = do(logical.CreateOperation, "accounts/"+publicKey+"/deposit-data", nil)
+			require.Equal(t, test.forkVersion, res.Data["fork_version"])
+
+			// storage of the network can't be decoded by the key manager library
+			storageReq := logical.TestRequest(t, logical.CreateOperation, "storage")
+			storageReq.Storage = req.Storage
+			storageReq.Data = map[string]interface{}{
+				"data": hex.EncodeToString([]byte(`{"network":"` + hex.EncodeToString([]byte(test.name)) + `"}`)),
+			}
+			res, err = b.HandleRequest(ctx, storageReq)
+			require.NoError(t, err)
+			requireErrorCode(t, res, errorex.CodeBadRequest, "storage of network '"+test.name+"' is not supported")
+		})
+	}
+}
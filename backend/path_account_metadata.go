@@ -0,0 +1,181 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// AccountMetadataPattern is the path pattern for account metadata endpoint
+	AccountMetadataPattern = "accounts/" + publicKeyRegex + "/metadata"
+)
+
+// maxGraffitiLength is the size of the graffiti field of a beacon block.
+const maxGraffitiLength = 32
+
+func accountMetadataPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         AccountMetadataPattern,
+			HelpSynopsis:    "Manage account metadata",
+			HelpDescription: `Manage the labels, fee recipient, graffiti and status of the account`,
+			Fields: map[string]*framework.FieldSchema{
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Public key of the account",
+				},
+				"labels": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: "Labels of the account as key=value pairs",
+				},
+				"fee_recipient": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Execution address to receive the fees of proposed blocks",
+				},
+				"graffiti": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Graffiti of proposed blocks, up to 32 bytes",
+				},
+				"status": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Status of the account (active, paused), paused accounts don't sign",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathAccountMetadataWrite,
+				logical.UpdateOperation: b.pathAccountMetadataWrite,
+				logical.ReadOperation:   b.pathAccountMetadataRead,
+			},
+		},
+	}
+}
+
+func (b *backend) pathAccountMetadataRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	storage, publicKey, res, err := b.openAccountMetadataStore(ctx, req, data)
+	if res != nil || err != nil {
+		return res, err
+	}
+
+	metadata, err := storage.OpenAccountMetadata(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open account metadata")
+	}
+
+	return &logical.Response{
+		Data: metadataResponseData(metadata),
+	}, nil
+}
+
+func (b *backend) pathAccountMetadataWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	storage, publicKey, res, err := b.openAccountMetadataStore(ctx, req, data)
+	if res != nil || err != nil {
+		return res, err
+	}
+
+	metadata, err := storage.OpenAccountMetadata(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open account metadata")
+	}
+
+	// Only the given fields are updated
+	if labels, ok := data.GetOk("labels"); ok {
+		metadata.Labels = labels.(map[string]string)
+	}
+
+	if feeRecipient, ok := data.GetOk("fee_recipient"); ok {
+		address := feeRecipient.(string)
+		if len(address) > 0 {
+			addressBytes, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
+			if err != nil || len(addressBytes) != 20 {
+				return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid fee recipient"))
+			}
+			address = "0x" + hex.EncodeToString(addressBytes)
+		}
+		metadata.FeeRecipient = address
+	}
+
+	if graffiti, ok := data.GetOk("graffiti"); ok {
+		if len(graffiti.(string)) > maxGraffitiLength {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("graffiti is longer than 32 bytes"))
+		}
+		metadata.Graffiti = graffiti.(string)
+	}
+
+	paused := false
+	if status, ok := data.GetOk("status"); ok {
+		if !store.IsAccountStatus(status.(string)) {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid account status"))
+		}
+		paused = metadata.Status != store.AccountStatusPaused && status.(string) == store.AccountStatusPaused
+		metadata.Status = status.(string)
+	}
+
+	if err := storage.SaveAccountMetadata(publicKey, metadata); err != nil {
+		return nil, errors.Wrap(err, "failed to save account metadata")
+	}
+
+	if paused {
+		b.sendPauseEvents(ctx, req, storage, publicKey)
+	}
+
+	return &logical.Response{
+		Data: metadataResponseData(metadata),
+	}, nil
+}
+
+// openAccountMetadataStore returns the store and the public key of an existing account.
+// A response is returned if the account does not exist.
+func (b *backend) openAccountMetadataStore(ctx context.Context, req *logical.Request, data *framework.FieldData) (*store.HashicorpVaultStore, string, *logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, "", nil, errors.Wrap(err, "failed to get config")
+	}
+
+	publicKey := strings.ToLower(data.Get("public_key").(string))
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, "", nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+
+	// Open wallet, accounts are looked up by the account index
+	if _, err := vault.OpenKeyVault(&options); err != nil {
+		return nil, "", nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	if _, err := storage.AccountByPublicKey(publicKey); err != nil {
+		if err == wallet_hd.ErrAccountNotFound {
+			res, err := b.notFoundResponse()
+			return nil, "", res, err
+		}
+
+		return nil, "", nil, errors.Wrap(err, "failed to retrieve account")
+	}
+
+	return storage, publicKey, nil, nil
+}
+
+func metadataResponseData(metadata *store.AccountMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"labels":        metadata.Labels,
+		"fee_recipient": metadata.FeeRecipient,
+		"graffiti":      metadata.Graffiti,
+		"status":        metadata.Status,
+		"imported_at":   metadata.ImportedAt,
+	}
+}
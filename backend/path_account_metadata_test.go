@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountMetadata(t *testing.T) {
+	b, _ := getBackend(t)
+	publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+
+	t.Run("Successfully Write and Read Metadata", func(t *testing.T) {
+		ctx := context.Background()
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/metadata")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"labels":        map[string]interface{}{"owner": "alice", "client": "prysm"},
+			"fee_recipient": "0x5A0b54D5dc17e0AadC383d2db43B0a0D3E029c4C",
+			"graffiti":      "key-vault",
+		}
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "0x5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c", res.Data["fee_recipient"])
+
+		// partial update keeps the other fields
+		updateReq := logical.TestRequest(t, logical.UpdateOperation, "accounts/"+publicKey+"/metadata")
+		updateReq.Storage = req.Storage
+		updateReq.Data = map[string]interface{}{
+			"graffiti": "updated",
+		}
+		_, err = b.HandleRequest(ctx, updateReq)
+		require.NoError(t, err)
+
+		readReq := logical.TestRequest(t, logical.ReadOperation, "accounts/"+publicKey+"/metadata")
+		readReq.Storage = req.Storage
+		res, err = b.HandleRequest(ctx, readReq)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"owner": "alice", "client": "prysm"}, res.Data["labels"])
+		require.Equal(t, "0x5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c", res.Data["fee_recipient"])
+		require.Equal(t, "updated", res.Data["graffiti"])
+
+		// filter accounts by labels
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		listReq.Data = map[string]interface{}{
+			"label": []string{"owner=alice", "client=prysm"},
+		}
+		res, err = b.HandleRequest(ctx, listReq)
+		require.NoError(t, err)
+		require.Len(t, res.Data["accounts"], 1)
+
+		listReq.Data = map[string]interface{}{
+			"label": "owner=bob",
+		}
+		res, err = b.HandleRequest(ctx, listReq)
+		require.NoError(t, err)
+		require.Empty(t, res.Data["accounts"])
+	})
+
+	t.Run("Reject invalid fee recipient", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/metadata")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"fee_recipient": "0x5a0b",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject too long graffiti", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/metadata")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"graffiti": "0123456789012345678901234567890123456789",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Metadata of unknown account", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.ReadOperation, "accounts/"+publicKey[:95]+"0/metadata")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 404, res.Data["http_status_code"])
+	})
+}
@@ -3,37 +3,103 @@ package backend
 import (
 	"context"
 	"encoding/hex"
+	"strings"
 
 	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/pkg/errors"
 
 	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // Endpoints patterns
 const (
 	// AccountsPattern is the path pattern for list accounts endpoint
 	AccountsPattern = "accounts/"
+
+	// AccountPattern is the path pattern for account details endpoint
+	AccountPattern = "accounts/" + publicKeyRegex
 )
 
+// publicKeyRegex matches a hex encoded validator public key.
+const publicKeyRegex = "(?P<public_key>[0-9a-fA-F]{96})"
+
 func accountsPaths(b *backend) []*framework.Path {
 	return []*framework.Path{
 		&framework.Path{
 			Pattern:         AccountsPattern,
 			HelpSynopsis:    "List wallet accounts",
-			HelpDescription: ``,
-			Fields:          map[string]*framework.FieldSchema{},
-			ExistenceCheck:  b.pathExistenceCheck,
+			HelpDescription: `List the wallet accounts ordered by public key, optionally filtered and paginated`,
+			Fields: map[string]*framework.FieldSchema{
+				"label": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: "List only accounts with all the given key=value labels",
+				},
+				"public_key_prefix": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "List only accounts with a public key starting with the given HEX prefix",
+				},
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "List only the account with the given name",
+				},
+				"status": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "List only accounts with the given status (active, paused)",
+				},
+				"cursor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "List accounts after the given cursor, as returned in next_cursor",
+				},
+				"limit": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of accounts to list, 0 lists all",
+					Default:     0,
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ListOperation: b.pathWalletAccountsList,
 			},
 		},
+		&framework.Path{
+			Pattern:         AccountPattern,
+			HelpSynopsis:    "Read wallet account",
+			HelpDescription: `Read the details, status and latest signed messages of the account`,
+			Fields: map[string]*framework.FieldSchema{
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Public key of the account",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathWalletAccountRead,
+			},
+		},
 	}
 }
 
 func (b *backend) pathWalletAccountsList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	labels := data.Get("label").(map[string]string)
+	publicKeyPrefix := strings.ToLower(strings.TrimPrefix(data.Get("public_key_prefix").(string), "0x"))
+	name := data.Get("name").(string)
+	status := data.Get("status").(string)
+	cursor := strings.ToLower(data.Get("cursor").(string))
+	limit := data.Get("limit").(int)
+
+	if len(status) > 0 && !store.IsAccountStatus(status) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid account status"))
+	}
+
+	if limit < 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid limit"))
+	}
+
 	// Load config
 	config, err := b.configured(ctx, req)
 	if err != nil {
@@ -41,33 +107,158 @@ func (b *backend) pathWalletAccountsList(ctx context.Context, req *logical.Reque
 	}
 
 	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
 	options := vault.KeyVaultOptions{}
 	options.SetStorage(storage)
 
-	portfolio, err := vault.OpenKeyVault(&options)
-	if err != nil {
+	if _, err := vault.OpenKeyVault(&options); err != nil {
 		return nil, errors.Wrap(err, "failed to open key vault")
 	}
 
-	wallet, err := portfolio.Wallet()
+	// Accounts are listed from the account index, ordered by public key
+	publicKeys, err := storage.ListAccountPublicKeys()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve wallet by name")
+		return nil, errors.Wrap(err, "failed to list accounts")
 	}
 
 	var accounts []map[string]string
-	for _, a := range wallet.Accounts() {
-		accObj := map[string]string{
-			"id":               a.ID().String(),
-			"name":             a.Name(),
-			"validationPubKey": hex.EncodeToString(a.ValidatorPublicKey().Marshal()),
-			"withdrawalPubKey": hex.EncodeToString(a.WithdrawalPublicKey().Marshal()),
+	var nextCursor string
+	for _, publicKey := range publicKeys {
+		if publicKey <= cursor || !strings.HasPrefix(publicKey, publicKeyPrefix) {
+			continue
+		}
+
+		if len(labels) > 0 || len(status) > 0 {
+			metadata, err := storage.OpenAccountMetadata(publicKey)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to open account metadata")
+			}
+
+			if !metadata.MatchLabels(labels) || (len(status) > 0 && metadata.Status != status) {
+				continue
+			}
+		}
+
+		a, err := storage.AccountByPublicKey(publicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve account %s", publicKey)
+		}
+
+		if len(name) > 0 && a.Name() != name {
+			continue
+		}
+
+		// A page is only followed by a cursor if another account matches,
+		// the last listed account is the cursor of the next page
+		if limit > 0 && len(accounts) == limit {
+			nextCursor = accounts[len(accounts)-1]["validationPubKey"]
+			break
 		}
-		accounts = append(accounts, accObj)
+
+		accounts = append(accounts, accountResponseData(a))
+	}
+
+	responseData := map[string]interface{}{
+		"accounts": accounts,
+	}
+	if len(nextCursor) > 0 {
+		responseData["next_cursor"] = nextCursor
 	}
 
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"accounts": accounts,
-		},
+		Data: responseData,
 	}, nil
 }
+
+func (b *backend) pathWalletAccountRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	publicKey := strings.ToLower(data.Get("public_key").(string))
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+
+	if _, err := vault.OpenKeyVault(&options); err != nil {
+		return nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	account, err := storage.AccountByPublicKey(publicKey)
+	if err != nil {
+		if err == wallet_hd.ErrAccountNotFound {
+			return b.notFoundResponse()
+		}
+
+		return nil, errors.Wrap(err, "failed to retrieve account")
+	}
+
+	metadata, err := storage.OpenAccountMetadata(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open account metadata")
+	}
+
+	latestAttestation, err := storage.RetrieveLatestAttestation(account.ValidatorPublicKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve latest attestation")
+	}
+
+	latestProposal, err := storage.RetrieveLatestProposal(account.ValidatorPublicKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve latest proposal")
+	}
+
+	responseData := map[string]interface{}{
+		"status":             metadata.Status,
+		"imported_at":        metadata.ImportedAt,
+		"labels":             metadata.Labels,
+		"latest_attestation": nil,
+		"latest_proposal":    nil,
+	}
+	for key, value := range accountResponseData(account) {
+		responseData[key] = value
+	}
+
+	if latestAttestation != nil {
+		responseData["latest_attestation"] = map[string]interface{}{
+			"slot":         latestAttestation.Slot,
+			"source_epoch": latestAttestation.Source.Epoch,
+			"target_epoch": latestAttestation.Target.Epoch,
+		}
+	}
+
+	if latestProposal != nil {
+		responseData["latest_proposal"] = map[string]interface{}{
+			"slot":           latestProposal.Slot,
+			"proposer_index": latestProposal.ProposerIndex,
+		}
+	}
+
+	return &logical.Response{
+		Data: responseData,
+	}, nil
+}
+
+// accountResponseData returns the public fields of the account.
+func accountResponseData(account core.ValidatorAccount) map[string]string {
+	ret := map[string]string{
+		"id":               account.ID().String(),
+		"name":             account.Name(),
+		"type":             store.AccountType(account),
+		"validationPubKey": hex.EncodeToString(account.ValidatorPublicKey().Marshal()),
+		"withdrawalPubKey": "",
+	}
+	// Imported accounts may have no withdrawal key
+	if withdrawalPubKey := account.WithdrawalPublicKey(); withdrawalPubKey != nil {
+		ret["withdrawalPubKey"] = hex.EncodeToString(withdrawalPubKey.Marshal())
+	}
+	return ret
+}
@@ -15,7 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func getBackend(t *testing.T) (logical.Backend, logical.Storage) {
+func getBackend(t testing.TB) (logical.Backend, logical.Storage) {
 	config := &logical.BackendConfig{
 		Logger:      logging.NewVaultLogger(log.Trace),
 		System:      &logical.StaticSystemView{},
@@ -34,7 +34,7 @@ func getBackend(t *testing.T) (logical.Backend, logical.Storage) {
 	return b, config.StorageView
 }
 
-func setupBaseStorage(t *testing.T, req *logical.Request) {
+func setupBaseStorage(t testing.TB, req *logical.Request) {
 	entry, err := logical.StorageEntryJSON("config", Config{
 		Network: core.MainNetwork,
 	})
@@ -65,6 +65,131 @@ func TestAccountsList(t *testing.T) {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
-		require.Equal(t, keys, []string{"id", "name", "validationPubKey", "withdrawalPubKey"})
+		require.Equal(t, keys, []string{"id", "name", "type", "validationPubKey", "withdrawalPubKey"})
+	})
+}
+
+func TestAccountsListFilters(t *testing.T) {
+	b, _ := getBackend(t)
+	ctx := context.Background()
+
+	req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+	setupBaseStorage(t, req)
+	_, err := b.HandleRequest(ctx, req)
+	require.NoError(t, err)
+
+	var publicKeys []string
+	for i := 0; i < 3; i++ {
+		accountReq := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+		accountReq.Storage = req.Storage
+		res, err := b.HandleRequest(ctx, accountReq)
+		require.NoError(t, err)
+		publicKeys = append(publicKeys, res.Data["validationPubKey"].(string))
+	}
+	sort.Strings(publicKeys)
+
+	list := func(data map[string]interface{}) *logical.Response {
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		listReq.Data = data
+		res, err := b.HandleRequest(ctx, listReq)
+		require.NoError(t, err)
+		return res
+	}
+
+	listedKeys := func(res *logical.Response) []string {
+		var ret []string
+		for _, account := range res.Data["accounts"].([]map[string]string) {
+			ret = append(ret, account["validationPubKey"])
+		}
+		return ret
+	}
+
+	t.Run("paginate", func(t *testing.T) {
+		res := list(map[string]interface{}{"limit": 2})
+		require.Equal(t, publicKeys[:2], listedKeys(res))
+		require.Equal(t, publicKeys[1], res.Data["next_cursor"])
+
+		res = list(map[string]interface{}{"limit": 2, "cursor": res.Data["next_cursor"]})
+		require.Equal(t, publicKeys[2:], listedKeys(res))
+		require.NotContains(t, res.Data, "next_cursor")
+	})
+
+	t.Run("filter by public key prefix", func(t *testing.T) {
+		res := list(map[string]interface{}{"public_key_prefix": "0x" + publicKeys[1][:8]})
+		require.Equal(t, publicKeys[1:2], listedKeys(res))
+	})
+
+	t.Run("filter by name", func(t *testing.T) {
+		res := list(map[string]interface{}{"name": "account-1"})
+		require.Len(t, res.Data["accounts"], 1)
+		require.Equal(t, "account-1", res.Data["accounts"].([]map[string]string)[0]["name"])
+	})
+
+	t.Run("filter by status", func(t *testing.T) {
+		metadataReq := logical.TestRequest(t, logical.UpdateOperation, "accounts/"+publicKeys[0]+"/metadata")
+		metadataReq.Storage = req.Storage
+		metadataReq.Data = map[string]interface{}{
+			"status": "paused",
+		}
+		_, err := b.HandleRequest(ctx, metadataReq)
+		require.NoError(t, err)
+
+		res := list(map[string]interface{}{"status": "paused"})
+		require.Equal(t, publicKeys[:1], listedKeys(res))
+
+		res = list(map[string]interface{}{"status": "active"})
+		require.Equal(t, publicKeys[1:], listedKeys(res))
+
+		// no cursor if the remaining accounts don't match
+		res = list(map[string]interface{}{"status": "paused", "limit": 1})
+		require.Equal(t, publicKeys[:1], listedKeys(res))
+		require.NotContains(t, res.Data, "next_cursor")
+
+		res = list(map[string]interface{}{"status": "active", "limit": 1})
+		require.Equal(t, publicKeys[1:2], listedKeys(res))
+		require.Equal(t, publicKeys[1], res.Data["next_cursor"])
+
+		res = list(map[string]interface{}{"status": "unknown"})
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+}
+
+func TestAccountRead(t *testing.T) {
+	b, _ := getBackend(t)
+	ctx := context.Background()
+	publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+
+	t.Run("Successfully Read Account", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = basicAttestationData()
+		_, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+
+		readReq := logical.TestRequest(t, logical.ReadOperation, "accounts/"+publicKey)
+		readReq.Storage = req.Storage
+		res, err := b.HandleRequest(ctx, readReq)
+		require.NoError(t, err)
+		require.Equal(t, publicKey, res.Data["validationPubKey"])
+		require.Equal(t, "HD", res.Data["type"])
+		require.Equal(t, "active", res.Data["status"])
+		require.NotZero(t, res.Data["imported_at"])
+		require.Nil(t, res.Data["latest_proposal"])
+		require.Equal(t, map[string]interface{}{
+			"slot":         uint64(284115),
+			"source_epoch": uint64(8877),
+			"target_epoch": uint64(8878),
+		}, res.Data["latest_attestation"])
+	})
+
+	t.Run("Read unknown account", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.ReadOperation, "accounts/"+publicKey[:95]+"0")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, 404, res.Data["http_status_code"])
 	})
 }
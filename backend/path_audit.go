@@ -0,0 +1,291 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bloxapp/eth2-key-manager/validator_signer"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// AuditPattern is the path pattern for audit endpoint
+	AuditPattern = "audit/?"
+)
+
+// DefaultAuditRetention is the default duration audit records are kept, in seconds.
+const DefaultAuditRetention = 30 * 24 * 60 * 60
+
+func auditPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         AuditPattern,
+			HelpSynopsis:    "Read the signing audit log",
+			HelpDescription: `Read the signing decisions of the plugin, newest first`,
+			Fields: map[string]*framework.FieldSchema{
+				"from": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Return records from this time on, in seconds since the Unix epoch",
+					Default:     0,
+				},
+				"to": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Return records until this time, in seconds since the Unix epoch",
+					Default:     0,
+				},
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Return the records of this account only",
+					Default:     "",
+				},
+				"limit": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of records to return, all if 0",
+					Default:     0,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathReadAudit,
+			},
+		},
+	}
+}
+
+func (b *backend) pathReadAudit(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	from := data.Get("from").(int)
+	to := data.Get("to").(int)
+	limit := data.Get("limit").(int)
+	if from < 0 || to < 0 || (to > 0 && to < from) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid time range"))
+	}
+	if limit < 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid limit"))
+	}
+
+	filter := store.AuditFilter{
+		PublicKey: data.Get("public_key").(string),
+		Limit:     limit,
+	}
+	if len(filter.PublicKey) > 0 {
+		if _, err := store.NormalizePublicKey(filter.PublicKey); err != nil {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid public key"))
+		}
+	}
+	if from > 0 {
+		filter.From = time.Unix(int64(from), 0)
+	}
+	if to > 0 {
+		// The whole last second is included
+		filter.To = time.Unix(int64(to)+1, 0).Add(-time.Nanosecond)
+	}
+
+	vaultStore := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+	records, err := vaultStore.ListAuditRecords(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit records")
+	}
+
+	ret := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		ret[i] = map[string]interface{}{
+			"time":         time.Unix(0, record.Time).UTC().Format(time.RFC3339Nano),
+			"public_key":   record.PublicKey,
+			"message_type": record.MessageType,
+			"slot":         record.Slot,
+			"source_epoch": record.SourceEpoch,
+			"target_epoch": record.TargetEpoch,
+			"signing_root": record.SigningRoot,
+			"outcome":      record.Outcome,
+			"reason":       record.Reason,
+			"request_id":   record.RequestID,
+			"accessor":     record.Accessor,
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"records": ret,
+		},
+	}, nil
+}
+
+// recorded records the decision of the given sign handler in the audit log, the account stats and the metrics,
+// and sends slashable messages to the webhooks.
+// Failing to record doesn't fail the request, as a produced signature is already saved in the slashing history.
+func (b *backend) recorded(messageType string, handler framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		start := time.Now()
+		res, err := handler(ctx, req, data)
+
+		record := &store.AuditRecord{
+			Time:        b.clock().UnixNano(),
+			PublicKey:   strings.ToLower(data.Get("public_key").(string)),
+			MessageType: messageType,
+			RequestID:   req.ID,
+			Accessor:    req.ClientTokenAccessor,
+		}
+		record.Outcome, record.Reason = auditOutcome(res, err)
+		describeSignedMessage(record, data)
+		b.metrics.observeSign(messageType, record.Outcome, start)
+		b.logSignDecision(req, record)
+
+		// Only decisions about an account are stored, so invalid requests don't grow the storage
+		if accountDecision(record, res, err) {
+			vaultStore := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+			if auditErr := vaultStore.AppendAuditRecord(record); auditErr != nil {
+				b.requestLogger(req).Error("Failed to append audit record", "error", auditErr)
+			}
+			b.stats.add(record)
+		}
+
+		if record.Outcome == store.AuditOutcomeSlashable {
+			b.sendEvent(ctx, req, &WebhookEvent{
+				Type:        WebhookEventSlashable,
+				PublicKey:   record.PublicKey,
+				MessageType: record.MessageType,
+				Slot:        record.Slot,
+				SourceEpoch: record.SourceEpoch,
+				TargetEpoch: record.TargetEpoch,
+				Reason:      record.Reason,
+			})
+		}
+
+		return res, err
+	}
+}
+
+// pruneAuditRecords deletes the audit records older than the retention of the mount.
+func (b *backend) pruneAuditRecords(ctx context.Context, req *logical.Request) error {
+	config, err := storedConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	if config == nil || config.AuditRetention == 0 {
+		return nil
+	}
+
+	vaultStore := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	pruned, err := vaultStore.PruneAuditRecords(b.clock().Add(-time.Duration(config.AuditRetention) * time.Second))
+	if err != nil {
+		return errors.Wrap(err, "failed to prune audit records")
+	}
+
+	if pruned > 0 {
+		b.Logger().Info("Pruned audit records", "count", pruned)
+	}
+	return nil
+}
+
+// auditOutcome returns the outcome and the reason of a sign handler result.
+func auditOutcome(res *logical.Response, err error) (string, string) {
+	if err != nil {
+		return codeOutcome(errorex.CodeOf(err)), err.Error()
+	}
+
+	switch {
+	case res == nil:
+		return store.AuditOutcomeFailed, ""
+	case res.Data["signature"] != nil:
+		return store.AuditOutcomeSigned, ""
+	case res.IsError():
+		return store.AuditOutcomeFailed, res.Error().Error()
+	default:
+		code, message := responseError(res)
+		return codeOutcome(code), message
+	}
+}
+
+// accountDecision returns true if the sign handler decided about an account:
+// the public key is valid and the request was neither malformed nor for an unknown account.
+func accountDecision(record *store.AuditRecord, res *logical.Response, err error) bool {
+	if _, keyErr := store.NormalizePublicKey(record.PublicKey); keyErr != nil || record.Outcome == store.AuditOutcomeNotFound {
+		return false
+	}
+
+	code := errorex.CodeOf(err)
+	if err == nil && res != nil {
+		code, _ = responseError(res)
+	}
+	return code != errorex.CodeBadRequest
+}
+
+// codeOutcome returns the audit outcome of an error code.
+func codeOutcome(code errorex.ErrorCode) string {
+	switch {
+	case code.Slashable():
+		return store.AuditOutcomeSlashable
+	case code == errorex.CodeLocked:
+		return store.AuditOutcomeLocked
+	case code == errorex.CodeAccountNotFound:
+		return store.AuditOutcomeNotFound
+	case len(code) > 0:
+		return store.AuditOutcomeRejected
+	default:
+		return store.AuditOutcomeFailed
+	}
+}
+
+// describeSignedMessage sets the slot, the epochs and the signing root of the signed message.
+// Messages that can't be decoded are recorded without them.
+func describeSignedMessage(record *store.AuditRecord, data *framework.FieldData) {
+	switch record.MessageType {
+	case store.AuditMessageAttestation:
+		req, err := signAttestationRequest(data)
+		if err != nil {
+			return
+		}
+		record.Slot = req.Data.Slot
+		record.SourceEpoch = req.Data.Source.Epoch
+		record.TargetEpoch = req.Data.Target.Epoch
+		if root, err := validator_signer.PrepareAttestationReqForSigning(req); err == nil {
+			record.SigningRoot = hex.EncodeToString(root)
+		}
+	case store.AuditMessageProposal:
+		req, err := signProposalRequest(data)
+		if err != nil {
+			return
+		}
+		record.Slot = req.Data.Slot
+		if root, err := validator_signer.PrepareProposalReqForSigning(req); err == nil {
+			record.SigningRoot = hex.EncodeToString(root)
+		}
+	case store.AuditMessageAggregation:
+		req, err := signAggregationRequest(data)
+		if err != nil {
+			return
+		}
+		if root, err := validator_signer.PrepareReqForSigning(req); err == nil {
+			record.SigningRoot = hex.EncodeToString(root[:])
+		}
+	}
+}
+
+// responseError returns the code and the message of a response built with a status code.
+func responseError(res *logical.Response) (errorex.ErrorCode, string) {
+	body, ok := res.Data[logical.HTTPRawBody].(string)
+	if !ok {
+		return "", ""
+	}
+
+	var httpResponse struct {
+		Data struct {
+			Code    errorex.ErrorCode `json:"code"`
+			Message string            `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &httpResponse); err != nil {
+		return "", ""
+	}
+
+	return httpResponse.Data.Code, httpResponse.Data.Message
+}
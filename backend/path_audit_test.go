@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+func TestAuditLog(t *testing.T) {
+	lb, _ := getBackend(t)
+	b := lb.(*backend)
+
+	now := time.Unix(1600000000, 0)
+	b.clock = func() time.Time {
+		return now
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Data = map[string]interface{}{
+		"network":         "test",
+		"audit_retention": "1h",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	sign := func(path string, data map[string]interface{}) {
+		signReq := logical.TestRequest(t, logical.CreateOperation, path)
+		signReq.Storage = req.Storage
+		signReq.ClientTokenAccessor = "signer-accessor"
+		signReq.Data = data
+		b.HandleRequest(context.Background(), signReq)
+		now = now.Add(time.Minute)
+	}
+
+	readAudit := func(data map[string]interface{}) []map[string]interface{} {
+		auditReq := logical.TestRequest(t, logical.ReadOperation, "audit")
+		auditReq.Storage = req.Storage
+		auditReq.Data = data
+		res, err := b.HandleRequest(context.Background(), auditReq)
+		require.NoError(t, err)
+		return res.Data["records"].([]map[string]interface{})
+	}
+
+	sign("accounts/sign-attestation", basicAttestationData())
+	slashable := basicAttestationData()
+	slashable["beaconBlockRoot"] = "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0"
+	sign("accounts/sign-attestation", slashable)
+	sign("accounts/sign-proposal", basicProposalData())
+	unknown := basicProposalData()
+	unknown["public_key"] = "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3270"
+	sign("accounts/sign-proposal", unknown)
+	invalid := basicAttestationData()
+	invalid["public_key"] = "ab32"
+	sign("accounts/sign-attestation", invalid)
+
+	t.Run("Signing decisions are recorded", func(t *testing.T) {
+		records := readAudit(nil)
+		require.Len(t, records, 3)
+
+		require.Equal(t, store.AuditOutcomeSigned, records[0]["outcome"])
+		require.Equal(t, store.AuditMessageProposal, records[0]["message_type"])
+		require.EqualValues(t, 284115, records[0]["slot"])
+		require.Equal(t, store.AuditOutcomeSlashable, records[1]["outcome"])
+		require.Equal(t, store.AuditOutcomeSigned, records[2]["outcome"])
+		require.EqualValues(t, 8877, records[2]["source_epoch"])
+		require.EqualValues(t, 8878, records[2]["target_epoch"])
+		require.Len(t, records[2]["signing_root"], 64)
+		require.Equal(t, "signer-accessor", records[2]["accessor"])
+	})
+
+	t.Run("Requests without account are not recorded", func(t *testing.T) {
+		records := readAudit(map[string]interface{}{
+			"public_key": unknown["public_key"],
+		})
+		require.Len(t, records, 0)
+	})
+
+	t.Run("Filter records", func(t *testing.T) {
+		records := readAudit(map[string]interface{}{
+			"public_key": basicAttestationData()["public_key"],
+			"from":       1600000060,
+		})
+		require.Len(t, records, 2)
+		require.Equal(t, store.AuditOutcomeSlashable, records[1]["outcome"])
+
+		records = readAudit(map[string]interface{}{
+			"to":    1600000060,
+			"limit": 1,
+		})
+		require.Len(t, records, 1)
+		require.Equal(t, store.AuditOutcomeSlashable, records[0]["outcome"])
+	})
+
+	t.Run("Reject invalid public key filter", func(t *testing.T) {
+		auditReq := logical.TestRequest(t, logical.ReadOperation, "audit")
+		auditReq.Storage = req.Storage
+		auditReq.Data = map[string]interface{}{"public_key": "ab32"}
+		res, err := b.HandleRequest(context.Background(), auditReq)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeBadRequest, "invalid public key")
+	})
+
+	t.Run("Records older than the retention are pruned", func(t *testing.T) {
+		now = time.Unix(1600000000+3600+90, 0)
+		require.NoError(t, b.pruneAuditRecords(context.Background(), req))
+		require.Len(t, readAudit(nil), 1)
+	})
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// BackupPattern is the path pattern for backup endpoint
+	BackupPattern = "backup"
+
+	// RestorePattern is the path pattern for restore endpoint
+	RestorePattern = "restore"
+)
+
+func backupPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         BackupPattern,
+			HelpSynopsis:    "Backup mount",
+			HelpDescription: `Create a password-encrypted backup of the config, wallet, accounts and slashing history`,
+			Fields: map[string]*framework.FieldSchema{
+				"password": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Password to encrypt the backup with",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathBackup,
+			},
+		},
+		&framework.Path{
+			Pattern:         RestorePattern,
+			HelpSynopsis:    "Restore mount",
+			HelpDescription: `Restore the config, wallet, accounts and slashing history from a backup`,
+			Fields: map[string]*framework.FieldSchema{
+				"backup": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "HEX encoded backup",
+				},
+				"password": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Password the backup was encrypted with",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathRestore,
+			},
+		},
+	}
+}
+
+func (b *backend) pathBackup(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	password := data.Get("password").(string)
+	if len(password) == 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("password is required"))
+	}
+
+	// Wallet changes and signing wait for the export, so the backup is a consistent snapshot
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+	b.signLock.Lock()
+	defer b.signLock.Unlock()
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	backup, err := storage.Backup(password)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create backup")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"backup": hex.EncodeToString(backup),
+		},
+	}, nil
+}
+
+func (b *backend) pathRestore(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	password := data.Get("password").(string)
+	backup, err := hex.DecodeString(data.Get("backup").(string))
+	if err != nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("failed to HEX decode backup"))
+	}
+
+	// Wallet changes and signing wait for the restore
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+	b.signLock.Lock()
+	defer b.signLock.Unlock()
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	if err := storage.Restore(backup, password); err != nil {
+		switch errors.Cause(err) {
+		case store.ErrInvalidBackup, store.ErrSlashingHistoryRollback:
+			b.sendEvent(ctx, req, &WebhookEvent{
+				Type:   WebhookEventIntegrityFailure,
+				Reason: err.Error(),
+			})
+			return b.prepareErrorResponse(errorex.NewErrBadRequest(err.Error()))
+		default:
+			return nil, errors.Wrap(err, "failed to restore backup")
+		}
+	}
+
+	// Backups of older schema versions are upgraded right away
+	if _, err := b.migrate(ctx, req.Storage); err != nil {
+		return nil, errors.Wrap(err, "failed to migrate storage")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"status": true,
+		},
+	}, nil
+}
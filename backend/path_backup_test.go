@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	b, _ := getBackend(t)
+	ctx := context.Background()
+
+	req := logical.TestRequest(t, logical.CreateOperation, "backup")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+	req.Data = map[string]interface{}{
+		"password": "password",
+	}
+	res, err := b.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	backup := res.Data["backup"].(string)
+	require.NotEmpty(t, backup)
+
+	t.Run("Successfully Restore Backup", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "restore")
+		req.Data = map[string]interface{}{
+			"backup":   backup,
+			"password": "password",
+		}
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.Data["status"].(bool))
+
+		// the restored mount is configured and lists the accounts
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		res, err = b.HandleRequest(ctx, listReq)
+		require.NoError(t, err)
+		require.Len(t, res.Data["accounts"], 1)
+	})
+
+	t.Run("Reject wrong password", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "restore")
+		req.Data = map[string]interface{}{
+			"backup":   backup,
+			"password": "wrong",
+		}
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject backup without password", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "backup")
+		setupBaseStorage(t, req)
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Backup waits for signing in progress", func(t *testing.T) {
+		backend := b.(*backend)
+
+		// a signature being recorded
+		backend.signLock.RLock()
+
+		done := make(chan error, 1)
+		var res *logical.Response
+		go func() {
+			var err error
+			res, err = b.HandleRequest(ctx, req)
+			done <- err
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("backup was exported while signing")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		backend.signLock.RUnlock()
+		require.NoError(t, <-done)
+		require.NotEmpty(t, res.Data["backup"])
+	})
+}
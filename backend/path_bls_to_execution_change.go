@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// BLSToExecutionChangePattern is the path pattern for BLS to execution change endpoint
+	BLSToExecutionChangePattern = "accounts/" + publicKeyRegex + "/bls-to-execution-change"
+)
+
+// domainBLSToExecutionChange is the signature domain type of BLS to execution change messages.
+var domainBLSToExecutionChange = e2types.DomainType{0x0a, 0, 0, 0}
+
+// blsToExecutionChange is the SSZ container of the BLS to execution change message.
+type blsToExecutionChange struct {
+	ValidatorIndex     uint64
+	FromBLSPubkey      []byte `ssz-size:"48"`
+	ToExecutionAddress []byte `ssz-size:"20"`
+}
+
+func blsToExecutionChangePaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         BLSToExecutionChangePattern,
+			HelpSynopsis:    "Sign BLS to execution change",
+			HelpDescription: `Sign a BLS to execution change message with the account's withdrawal key`,
+			Fields: map[string]*framework.FieldSchema{
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Public key of the account",
+				},
+				"validator_index": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Index of the validator",
+					Default:     0,
+				},
+				"execution_address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Execution address to change the withdrawal credentials to",
+					Default:     "",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathSignBLSToExecutionChange,
+			},
+		},
+	}
+}
+
+func (b *backend) pathSignBLSToExecutionChange(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	if len(config.GenesisValidatorsRoot) == 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("genesis validators root is not configured"))
+	}
+
+	// Parse request data
+	publicKey := strings.ToLower(data.Get("public_key").(string))
+	validatorIndex := data.Get("validator_index").(int)
+	executionAddress := data.Get("execution_address").(string)
+
+	if validatorIndex < 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid validator index"))
+	}
+
+	executionAddressBytes, err := hex.DecodeString(strings.TrimPrefix(executionAddress, "0x"))
+	if err != nil || len(executionAddressBytes) != 20 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid execution address"))
+	}
+
+	// bring up KeyVault and wallet
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+
+	// Open wallet, accounts are looked up by the account index
+	if _, err := vault.OpenKeyVault(&options); err != nil {
+		return nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	account, err := storage.AccountByPublicKey(publicKey)
+	if err != nil {
+		if err == wallet_hd.ErrAccountNotFound {
+			return b.notFoundResponse()
+		}
+
+		return nil, errors.Wrap(err, "failed to retrieve account")
+	}
+
+	withdrawalKey, err := deriveWithdrawalKey(storage, account, config.Network)
+	if err != nil {
+		return b.prepareErrorResponse(err)
+	}
+
+	// Sign the message
+	message := &blsToExecutionChange{
+		ValidatorIndex:     uint64(validatorIndex),
+		FromBLSPubkey:      withdrawalKey.PublicKey().Marshal(),
+		ToExecutionAddress: executionAddressBytes,
+	}
+
+	domain := e2types.Domain(domainBLSToExecutionChange, config.genesisForkVersion(), config.GenesisValidatorsRoot)
+	root, err := computeSigningRoot(message, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := withdrawalKey.Sign(root[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign BLS to execution change")
+	}
+
+	// Respond in the beacon node API format
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"message": map[string]interface{}{
+				"validator_index":      strconv.FormatUint(message.ValidatorIndex, 10),
+				"from_bls_pubkey":      "0x" + hex.EncodeToString(message.FromBLSPubkey),
+				"to_execution_address": "0x" + hex.EncodeToString(message.ToExecutionAddress),
+			},
+			"signature": "0x" + hex.EncodeToString(signature.Marshal()),
+		},
+	}, nil
+}
+
+// deriveWithdrawalKey derives the withdrawal key of the given account from the wallet seed stored in vault.
+func deriveWithdrawalKey(storage *store.HashicorpVaultStore, account core.ValidatorAccount, network core.Network) (*core.HDKey, error) {
+	seed, err := storage.OpenWalletSeed()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open wallet seed")
+	}
+
+	if seed == nil {
+		return nil, errorex.NewErrBadRequest("withdrawal key is not available, wallet seed is not stored in vault")
+	}
+
+	// Imported accounts have no base path
+	if len(account.BasePath()) == 0 {
+		return nil, errorex.NewErrBadRequest("withdrawal key is not available, account was not derived from the wallet seed")
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(account.BasePath(), "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid account base path %s", account.BasePath())
+	}
+
+	masterKey, err := core.MasterKeyFromSeed(seed, network)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create master key")
+	}
+
+	key, err := masterKey.Derive(fmt.Sprintf(wallet_hd.WithdrawalKeyPath, index))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive withdrawal key")
+	}
+
+	// Make sure the account was derived from the stored seed
+	if !bytes.Equal(key.PublicKey().Marshal(), account.WithdrawalPublicKey().Marshal()) {
+		return nil, errorex.NewErrBadRequest("withdrawal key is not available, account was not derived from the wallet seed")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+const (
+	testGenesisValidatorsRoot = "4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"
+	testExecutionAddress      = "0x5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c"
+)
+
+func setupGenesisStorage(t *testing.T, req *logical.Request) {
+	entry, err := logical.StorageEntryJSON("config", Config{
+		Network:               core.MainNetwork,
+		GenesisValidatorsRoot: _byteArray(testGenesisValidatorsRoot),
+	})
+	require.NoError(t, err)
+	req.Storage.Put(context.Background(), entry)
+}
+
+func TestSignBLSToExecutionChange(t *testing.T) {
+	b, _ := getBackend(t)
+
+	t.Run("Successfully Sign BLS to execution change", func(t *testing.T) {
+		ctx := context.Background()
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupGenesisStorage(t, req)
+		_, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+
+		accountReq := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+		accountReq.Storage = req.Storage
+		account, err := b.HandleRequest(ctx, accountReq)
+		require.NoError(t, err)
+		withdrawalPubKey := account.Data["withdrawalPubKey"].(string)
+
+		signReq := logical.TestRequest(t, logical.CreateOperation, "accounts/"+account.Data["validationPubKey"].(string)+"/bls-to-execution-change")
+		signReq.Storage = req.Storage
+		signReq.Data = map[string]interface{}{
+			"validator_index":   123,
+			"execution_address": testExecutionAddress,
+		}
+		res, err := b.HandleRequest(ctx, signReq)
+		require.NoError(t, err)
+
+		message := res.Data["message"].(map[string]interface{})
+		require.Equal(t, "123", message["validator_index"])
+		require.Equal(t, "0x"+withdrawalPubKey, message["from_bls_pubkey"])
+		require.Equal(t, testExecutionAddress, message["to_execution_address"])
+
+		// verify the signature with the withdrawal public key
+		pubKey, err := e2types.BLSPublicKeyFromBytes(_byteArray(withdrawalPubKey))
+		require.NoError(t, err)
+		sig, err := e2types.BLSSignatureFromBytes(_byteArray(strings.TrimPrefix(res.Data["signature"].(string), "0x")))
+		require.NoError(t, err)
+
+		domain := e2types.Domain(domainBLSToExecutionChange, core.MainNetwork.ForkVersion(), _byteArray(testGenesisValidatorsRoot))
+		root, err := computeSigningRoot(&blsToExecutionChange{
+			ValidatorIndex:     123,
+			FromBLSPubkey:      pubKey.Marshal(),
+			ToExecutionAddress: _byteArray(strings.TrimPrefix(testExecutionAddress, "0x")),
+		}, domain)
+		require.NoError(t, err)
+		require.True(t, sig.Verify(root[:], pubKey))
+	})
+
+	t.Run("Reject account without wallet seed", func(t *testing.T) {
+		publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/bls-to-execution-change")
+		setupGenesisStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"validator_index":   123,
+			"execution_address": testExecutionAddress,
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject without genesis validators root", func(t *testing.T) {
+		publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/bls-to-execution-change")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"validator_index":   123,
+			"execution_address": testExecutionAddress,
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject invalid execution address", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+hex.EncodeToString(make([]byte, 48))+"/bls-to-execution-change")
+		setupGenesisStorage(t, req)
+		req.Data = map[string]interface{}{
+			"validator_index":   123,
+			"execution_address": "0x5a0b",
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+}
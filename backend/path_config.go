@@ -1,14 +1,22 @@
 package backend
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // Endpoints patterns
@@ -19,7 +27,15 @@ const (
 
 // Config contains the configuration for each mount
 type Config struct {
-	Network core.Network `json:"network"`
+	Network               core.Network `json:"network"`
+	GenesisValidatorsRoot []byte       `json:"genesis_validators_root"`
+	GenesisForkVersion    []byte       `json:"genesis_fork_version,omitempty"`
+	GenesisTime           uint64       `json:"genesis_time,omitempty"`
+	ForkSchedule          []Fork       `json:"fork_schedule,omitempty"`
+	SecondsPerSlot        uint64       `json:"seconds_per_slot,omitempty"`
+	SlotWindow            uint64       `json:"slot_window,omitempty"`
+	AuditRetention        int64        `json:"audit_retention,omitempty"`
+	LogLevel              string       `json:"log_level,omitempty"`
 }
 
 func configPaths(b *backend) []*framework.Path {
@@ -37,12 +53,59 @@ func configPaths(b *backend) []*framework.Path {
 				"network": {
 					Type: framework.TypeString,
 					Description: `Ethereum network - can be one of the following values:
+					mainnet    - Ethereum Mainnet
+					prater     - Prater (Goerli) Test Network, goerli is an alias
+					sepolia    - Sepolia Test Network
+					holesky    - Holesky Test Network
+					custom     - Network defined by the genesis and fork schedule fields
 					launchtest - Launch Test Network
-					test 	   - Goerli Test Network`,
-					AllowedValues: []interface{}{
-						string(core.TestNetwork),
-						string(core.LaunchTestNetwork),
-					},
+					test 	   - Goerli Test Network
+					main       - Key manager main network`,
+					AllowedValues: networkNames(),
+				},
+				"genesis_validators_root": {
+					Type:        framework.TypeString,
+					Description: "Hex encoded genesis validators root of the network. Required for custom networks",
+					Default:     "",
+				},
+				"genesis_fork_version": {
+					Type:        framework.TypeString,
+					Description: "Hex encoded genesis fork version of a custom network",
+					Default:     "",
+				},
+				"fork_schedule": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Forks of a custom network as <epoch>:<hex encoded fork version>",
+				},
+				"genesis_time": {
+					Type:        framework.TypeInt,
+					Description: "Genesis time of the network, in seconds since the Unix epoch. Built in for the public networks and required for custom networks",
+					Default:     0,
+				},
+				"seconds_per_slot": {
+					Type:        framework.TypeInt,
+					Description: "Slot duration of the network in seconds",
+					Default:     DefaultSecondsPerSlot,
+				},
+				"slot_window": {
+					Type:        framework.TypeInt,
+					Description: "Number of slots a signed slot may be before or after the current wall-clock slot, 0 disables the check",
+					Default:     DefaultSlotWindow,
+				},
+				"audit_retention": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Duration the signing audit records are kept, 0 keeps them forever",
+					Default:     DefaultAuditRetention,
+				},
+				"log_level": {
+					Type:        framework.TypeString,
+					Description: "Log level of the mount: trace, debug, info, warn or error. The level of the plugin is used if empty",
+					Default:     "",
+				},
+				"force": {
+					Type:        framework.TypeBool,
+					Description: "Change the network of a mount that already has a wallet",
+					Default:     false,
 				},
 			},
 		},
@@ -51,10 +114,49 @@ func configPaths(b *backend) []*framework.Path {
 
 // pathWriteConfig is the write config path handler
 func (b *backend) pathWriteConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	network := data.Get("network").(string)
+	configBundle, err := configFromFieldData(data)
+	if err != nil {
+		return b.prepareErrorResponse(err)
+	}
+	forced := data.Get("force").(bool)
+
+	previous, err := storedConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
 
-	configBundle := Config{
-		Network: core.NetworkFromString(network),
+	// Accounts and slashing history belong to the network they were created on.
+	// A forced change keeps the slashing history, and requires a backup covering it,
+	// so the mount can be restored if the change was a mistake.
+	var networkChanged bool
+	if previous != nil && !previous.sameNetwork(configBundle) {
+		wallet, err := req.Storage.Get(ctx, store.WalletDataPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get wallet")
+		}
+
+		if wallet != nil {
+			if !forced {
+				return b.prepareErrorResponse(errorex.NewErrBadRequest("the network of a mount with a wallet can't be changed without force"))
+			}
+			networkChanged = true
+		}
+	}
+
+	// Signing waits for the change, so the backup still covers the slashing history when the config is written
+	if networkChanged {
+		b.signLock.Lock()
+		defer b.signLock.Unlock()
+
+		storage := store.NewHashicorpVaultStore(ctx, req.Storage, previous.Network)
+		storage.SetLogger(b.requestLogger(req))
+		backedUp, err := storage.SlashingHistoryBackedUp()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check the last backup")
+		}
+		if !backedUp {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("the network of a mount with a wallet can only be changed after a backup of its slashing history"))
+		}
 	}
 
 	// Create storage entry
@@ -68,12 +170,153 @@ func (b *backend) pathWriteConfig(ctx context.Context, req *logical.Request, dat
 		return nil, err
 	}
 
-	// Return the secret
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"network": configBundle.Network,
-		},
-	}, nil
+	if err := b.recordConfigChange(ctx, req, previous, configBundle, forced); err != nil {
+		return nil, errors.Wrap(err, "failed to record config change")
+	}
+	b.setLogLevel(configBundle)
+	b.requestLogger(req).Info("Updated config", "network", configBundle.Network, "forced", forced, "accessor", req.ClientTokenAccessor)
+
+	// Drop the cached config
+	if err := store.RotateGeneration(ctx, req.Storage); err != nil {
+		return nil, errors.Wrap(err, "failed to rotate storage generation")
+	}
+
+	res := &logical.Response{
+		Data: configBundle.toResponseData(),
+	}
+	if networkChanged {
+		res.AddWarning(fmt.Sprintf("the network was changed from %s to %s, the slashing history of the accounts is kept", previous.Network, configBundle.Network))
+	}
+	return res, nil
+}
+
+// configFromFieldData builds the config of the requested network.
+// Known networks are completed with their parameters, custom networks must provide them.
+func configFromFieldData(data *framework.FieldData) (*Config, error) {
+	network := core.Network(data.Get("network").(string))
+	if network == GoerliNetwork {
+		network = PraterNetwork
+	}
+
+	genesisValidatorsRoot, err := hex.DecodeString(strings.TrimPrefix(data.Get("genesis_validators_root").(string), "0x"))
+	if err != nil || (len(genesisValidatorsRoot) != 0 && len(genesisValidatorsRoot) != 32) {
+		return nil, errorex.NewErrBadRequest("invalid genesis validators root")
+	}
+
+	genesisForkVersion, err := hex.DecodeString(strings.TrimPrefix(data.Get("genesis_fork_version").(string), "0x"))
+	if err != nil || (len(genesisForkVersion) != 0 && len(genesisForkVersion) != 4) {
+		return nil, errorex.NewErrBadRequest("invalid genesis fork version")
+	}
+
+	genesisTime := data.Get("genesis_time").(int)
+	if genesisTime < 0 {
+		return nil, errorex.NewErrBadRequest("invalid genesis time")
+	}
+
+	forkSchedule, err := parseForkSchedule(data.Get("fork_schedule").([]string))
+	if err != nil {
+		return nil, err
+	}
+
+	secondsPerSlot := data.Get("seconds_per_slot").(int)
+	if secondsPerSlot <= 0 {
+		return nil, errorex.NewErrBadRequest("invalid seconds per slot")
+	}
+
+	slotWindow := data.Get("slot_window").(int)
+	if slotWindow < 0 {
+		return nil, errorex.NewErrBadRequest("invalid slot window")
+	}
+
+	auditRetention := data.Get("audit_retention").(int)
+	if auditRetention < 0 {
+		return nil, errorex.NewErrBadRequest("invalid audit retention")
+	}
+
+	logLevel := strings.ToLower(strings.TrimSpace(data.Get("log_level").(string)))
+	if len(logLevel) > 0 && hclog.LevelFromString(logLevel) == hclog.NoLevel {
+		return nil, errorex.NewErrBadRequest("invalid log level")
+	}
+
+	config := &Config{
+		Network:               network,
+		GenesisValidatorsRoot: genesisValidatorsRoot,
+		GenesisForkVersion:    genesisForkVersion,
+		GenesisTime:           uint64(genesisTime),
+		ForkSchedule:          forkSchedule,
+		SecondsPerSlot:        uint64(secondsPerSlot),
+		SlotWindow:            uint64(slotWindow),
+		AuditRetention:        int64(auditRetention),
+		LogLevel:              logLevel,
+	}
+
+	if network == CustomNetwork {
+		switch {
+		case len(config.GenesisForkVersion) == 0:
+			return nil, errorex.NewErrBadRequest("genesis fork version is required for custom networks")
+		case len(config.GenesisValidatorsRoot) == 0:
+			return nil, errorex.NewErrBadRequest("genesis validators root is required for custom networks")
+		case config.GenesisTime == 0:
+			return nil, errorex.NewErrBadRequest("genesis time is required for custom networks")
+		}
+		return config, nil
+	}
+
+	spec := knownNetwork(network)
+	if spec == nil {
+		return nil, errorex.NewErrBadRequest("invalid network")
+	}
+
+	// The parameters of known networks can't be overridden
+	switch {
+	case len(config.ForkSchedule) > 0:
+		return nil, errorex.NewErrBadRequest("fork schedule is only allowed for custom networks")
+	case len(config.GenesisForkVersion) > 0 && !bytes.Equal(config.GenesisForkVersion, spec.genesisForkVersion):
+		return nil, errorex.NewErrBadRequest("genesis fork version doesn't match the network")
+	case len(config.GenesisValidatorsRoot) > 0 && len(spec.genesisValidatorsRoot) > 0 && !bytes.Equal(config.GenesisValidatorsRoot, spec.genesisValidatorsRoot):
+		return nil, errorex.NewErrBadRequest("genesis validators root doesn't match the network")
+	case config.GenesisTime != 0 && spec.genesisTime != 0 && config.GenesisTime != spec.genesisTime:
+		return nil, errorex.NewErrBadRequest("genesis time doesn't match the network")
+	case spec.genesisTime != 0 && config.SecondsPerSlot != DefaultSecondsPerSlot:
+		return nil, errorex.NewErrBadRequest("seconds per slot doesn't match the network")
+	}
+
+	config.GenesisForkVersion = spec.genesisForkVersion
+	config.ForkSchedule = spec.forkSchedule
+	if len(spec.genesisValidatorsRoot) > 0 {
+		config.GenesisValidatorsRoot = spec.genesisValidatorsRoot
+	}
+	if spec.genesisTime != 0 {
+		config.GenesisTime = spec.genesisTime
+	}
+
+	return config, nil
+}
+
+// parseForkSchedule parses forks given as <epoch>:<hex encoded fork version>.
+func parseForkSchedule(values []string) ([]Fork, error) {
+	forks := make([]Fork, 0, len(values))
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) != 2 {
+			return nil, errorex.NewErrBadRequest(fmt.Sprintf("invalid fork %s", value))
+		}
+
+		epoch, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			return nil, errorex.NewErrBadRequest(fmt.Sprintf("invalid fork epoch %s", parts[0]))
+		}
+
+		version, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(parts[1]), "0x"))
+		if err != nil || len(version) != 4 {
+			return nil, errorex.NewErrBadRequest(fmt.Sprintf("invalid fork version %s", parts[1]))
+		}
+
+		forks = append(forks, Fork{Epoch: epoch, Version: version})
+	}
+
+	sortForkSchedule(forks)
+	return forks, nil
 }
 
 // pathReadConfig is the read config path handler
@@ -89,33 +332,79 @@ func (b *backend) pathReadConfig(ctx context.Context, req *logical.Request, data
 
 	// Return the secret
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"network": configBundle.Network,
-		},
+		Data: configBundle.toResponseData(),
 	}, nil
 }
 
 // readConfig returns the configuration for this PluginBackend.
 func (b *backend) readConfig(ctx context.Context, s logical.Storage) (*Config, error) {
+	config, err := storedConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, errorex.NewCodedError(errorex.CodeNotConfigured, "the plugin has not been configured yet")
+	}
+
+	return config, nil
+}
+
+// storedConfig returns the stored configuration, nil if the plugin has not been configured yet.
+func storedConfig(ctx context.Context, s logical.Storage) (*Config, error) {
 	entry, err := s.Get(ctx, "config")
 	if err != nil {
 		return nil, err
 	}
 
 	if entry == nil {
-		return nil, fmt.Errorf("the plugin has not been configured yet")
+		return nil, nil
 	}
 
 	var result Config
-	if entry != nil {
-		if err := entry.DecodeJSON(&result); err != nil {
-			return nil, errors.Wrap(err, "error reading configuration")
-		}
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, errors.Wrap(err, "error reading configuration")
 	}
 
 	return &result, nil
 }
 
+// sameNetwork returns true if both configs describe the same network.
+// Adding a genesis validators root to a config that had none doesn't change its network.
+func (config *Config) sameNetwork(other *Config) bool {
+	if config.Network != other.Network || !bytes.Equal(config.genesisForkVersion(), other.genesisForkVersion()) {
+		return false
+	}
+
+	if len(config.GenesisValidatorsRoot) > 0 && len(other.GenesisValidatorsRoot) > 0 {
+		return bytes.Equal(config.GenesisValidatorsRoot, other.GenesisValidatorsRoot)
+	}
+	return true
+}
+
+// toResponseData returns the config representation for responses.
+func (config *Config) toResponseData() map[string]interface{} {
+	forkSchedule := make([]map[string]interface{}, len(config.ForkSchedule))
+	for i, fork := range config.ForkSchedule {
+		forkSchedule[i] = map[string]interface{}{
+			"epoch":   fork.Epoch,
+			"version": hex.EncodeToString(fork.Version),
+		}
+	}
+
+	return map[string]interface{}{
+		"network":                 config.Network,
+		"genesis_validators_root": hex.EncodeToString(config.GenesisValidatorsRoot),
+		"genesis_fork_version":    hex.EncodeToString(config.genesisForkVersion()),
+		"genesis_time":            config.GenesisTime,
+		"fork_schedule":           forkSchedule,
+		"seconds_per_slot":        config.SecondsPerSlot,
+		"slot_window":             config.SlotWindow,
+		"audit_retention":         config.AuditRetention,
+		"log_level":               config.LogLevel,
+	}
+}
+
 func (b *backend) configured(ctx context.Context, req *logical.Request) (*Config, error) {
 	config, err := b.readConfig(ctx, req.Storage)
 	if err != nil {
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// ConfigHistoryPattern is the path pattern for config history endpoint
+	ConfigHistoryPattern = "config/history"
+)
+
+// Paths
+const (
+	configHistoryBase = "config/history/"
+	configHistoryPath = configHistoryBase + "%020d-%s" // change time in nanoseconds, request ID
+)
+
+// ConfigChange is a recorded write of the mount config.
+type ConfigChange struct {
+	// Time is the time of the change in nanoseconds since the Unix epoch
+	Time            int64        `json:"time"`
+	Accessor        string       `json:"accessor"`
+	DisplayName     string       `json:"display_name"`
+	Forced          bool         `json:"forced"`
+	PreviousNetwork core.Network `json:"previous_network,omitempty"`
+	Config          *Config      `json:"config"`
+}
+
+func configHistoryPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         ConfigHistoryPattern,
+			HelpSynopsis:    "Read the config history",
+			HelpDescription: `Read the changes of the mount config, newest first`,
+			Fields: map[string]*framework.FieldSchema{
+				"limit": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of changes to return, all if 0",
+					Default:     0,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathReadConfigHistory,
+			},
+		},
+	}
+}
+
+func (b *backend) pathReadConfigHistory(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	limit := data.Get("limit").(int)
+	if limit < 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid limit"))
+	}
+
+	keys, err := req.Storage.List(ctx, configHistoryBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list config history")
+	}
+
+	// Keys are sorted by time
+	changes := make([]map[string]interface{}, 0, len(keys))
+	for i := len(keys) - 1; i >= 0; i-- {
+		if limit > 0 && len(changes) == limit {
+			break
+		}
+
+		entry, err := req.Storage.Get(ctx, configHistoryBase+keys[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get config change %s", keys[i])
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		var change ConfigChange
+		if err := entry.DecodeJSON(&change); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode config change %s", keys[i])
+		}
+
+		changes = append(changes, map[string]interface{}{
+			"time":             time.Unix(0, change.Time).UTC().Format(time.RFC3339Nano),
+			"accessor":         change.Accessor,
+			"display_name":     change.DisplayName,
+			"forced":           change.Forced,
+			"previous_network": change.PreviousNetwork,
+			"config":           change.Config.toResponseData(),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"changes": changes,
+		},
+	}, nil
+}
+
+// recordConfigChange stores the config write of the given request in the config history.
+func (b *backend) recordConfigChange(ctx context.Context, req *logical.Request, previous *Config, config *Config, forced bool) error {
+	now := b.clock().UnixNano()
+	change := &ConfigChange{
+		Time:        now,
+		Accessor:    req.ClientTokenAccessor,
+		DisplayName: req.DisplayName,
+		Forced:      forced,
+		Config:      config,
+	}
+	if previous != nil {
+		change.PreviousNetwork = previous.Network
+	}
+
+	entry, err := logical.StorageEntryJSON(fmt.Sprintf(configHistoryPath, now, req.ID), change)
+	if err != nil {
+		return err
+	}
+
+	return req.Storage.Put(ctx, entry)
+}
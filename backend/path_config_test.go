@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+func TestConfig(t *testing.T) {
+	b, _ := getBackend(t)
+
+	writeConfig := func(t *testing.T, data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "config")
+		req.Data = data
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Configure mainnet", func(t *testing.T) {
+		res := writeConfig(t, map[string]interface{}{
+			"network": "mainnet",
+		})
+		require.Equal(t, MainnetNetwork, res.Data["network"])
+		require.Equal(t, "00000000", res.Data["genesis_fork_version"])
+		require.Equal(t, "4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95", res.Data["genesis_validators_root"])
+		require.EqualValues(t, 1606824023, res.Data["genesis_time"])
+		require.Len(t, res.Data["fork_schedule"], 4)
+		require.EqualValues(t, DefaultSecondsPerSlot, res.Data["seconds_per_slot"])
+		require.EqualValues(t, DefaultSlotWindow, res.Data["slot_window"])
+	})
+
+	t.Run("Goerli is an alias of Prater", func(t *testing.T) {
+		res := writeConfig(t, map[string]interface{}{
+			"network": "goerli",
+		})
+		require.Equal(t, PraterNetwork, res.Data["network"])
+		require.Equal(t, "00001020", res.Data["genesis_fork_version"])
+	})
+
+	t.Run("Legacy networks keep the key manager fork version", func(t *testing.T) {
+		res := writeConfig(t, map[string]interface{}{
+			"network": "test",
+		})
+		require.Equal(t, "00000001", res.Data["genesis_fork_version"])
+		require.Equal(t, "", res.Data["genesis_validators_root"])
+	})
+
+	t.Run("Configure custom network", func(t *testing.T) {
+		res := writeConfig(t, map[string]interface{}{
+			"network":                 "custom",
+			"genesis_fork_version":    "0x10000038",
+			"genesis_validators_root": "83431ec7fcf92cfc44947fc0418e831c25e1d0806590231c439830db7ad54fda",
+			"genesis_time":            1700000000,
+			"fork_schedule":           "20:30000038,10:20000038",
+		})
+		require.Equal(t, CustomNetwork, res.Data["network"])
+		require.Equal(t, "10000038", res.Data["genesis_fork_version"])
+		require.Equal(t, []map[string]interface{}{
+			{"epoch": uint64(10), "version": "20000038"},
+			{"epoch": uint64(20), "version": "30000038"},
+		}, res.Data["fork_schedule"])
+	})
+
+	t.Run("Set log level of the mount", func(t *testing.T) {
+		res := writeConfig(t, map[string]interface{}{
+			"network":   "test",
+			"log_level": "WARN",
+		})
+		require.Equal(t, "warn", res.Data["log_level"])
+		require.True(t, b.Logger().IsWarn())
+		require.False(t, b.Logger().IsInfo())
+
+		// The level of the plugin is restored
+		res = writeConfig(t, map[string]interface{}{
+			"network": "test",
+		})
+		require.Equal(t, "", res.Data["log_level"])
+		require.True(t, b.Logger().IsTrace())
+	})
+
+	t.Run("Reject invalid configs", func(t *testing.T) {
+		for name, data := range map[string]map[string]interface{}{
+			"unknown network": {"network": "unknown"},
+			"custom network without genesis": {
+				"network":                 "custom",
+				"genesis_validators_root": "83431ec7fcf92cfc44947fc0418e831c25e1d0806590231c439830db7ad54fda",
+			},
+			"invalid fork": {
+				"network":                 "custom",
+				"genesis_fork_version":    "10000038",
+				"genesis_validators_root": "83431ec7fcf92cfc44947fc0418e831c25e1d0806590231c439830db7ad54fda",
+				"genesis_time":            1700000000,
+				"fork_schedule":           "20-30000038",
+			},
+			"mismatching genesis validators root": {
+				"network":                 "mainnet",
+				"genesis_validators_root": "83431ec7fcf92cfc44947fc0418e831c25e1d0806590231c439830db7ad54fda",
+			},
+			"negative slot window": {
+				"network":     "test",
+				"slot_window": -1,
+			},
+			"fork schedule of known network": {
+				"network":       "sepolia",
+				"fork_schedule": "10:20000038",
+			},
+			"invalid log level": {
+				"network":   "test",
+				"log_level": "verbose",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				res := writeConfig(t, data)
+				require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+			})
+		}
+	})
+}
+
+func TestConfigNetworkChange(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.ClientTokenAccessor = "admin-accessor"
+	writeConfig := func(data map[string]interface{}) *logical.Response {
+		req.Data = data
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	writeConfig(map[string]interface{}{"network": "test"})
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	t.Run("Change settings of the network", func(t *testing.T) {
+		res := writeConfig(map[string]interface{}{"network": "test", "slot_window": 8})
+		require.EqualValues(t, 8, res.Data["slot_window"])
+	})
+
+	t.Run("Refuse network change with a wallet", func(t *testing.T) {
+		res := writeConfig(map[string]interface{}{"network": "launchtest"})
+		require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+	})
+
+	t.Run("Force network change with a wallet", func(t *testing.T) {
+		publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+		history := []string{"attestations/" + publicKey + "/5", "attestations/" + publicKey + "/latest", "proposals/" + publicKey + "/7"}
+		for _, key := range history {
+			require.NoError(t, req.Storage.Put(context.Background(), &logical.StorageEntry{Key: key, Value: []byte("{}")}))
+		}
+
+		backup := func() {
+			backupReq := logical.TestRequest(t, logical.CreateOperation, "backup")
+			backupReq.Storage = req.Storage
+			backupReq.Data = map[string]interface{}{"password": "password"}
+			res, err := b.HandleRequest(context.Background(), backupReq)
+			require.NoError(t, err)
+			require.NotEmpty(t, res.Data["backup"])
+		}
+
+		// the slashing history must be backed up first
+		res := writeConfig(map[string]interface{}{"network": "launchtest", "force": true})
+		requireErrorCode(t, res, errorex.CodeBadRequest, "the network of a mount with a wallet can only be changed after a backup of its slashing history")
+
+		// and signed messages since the backup are not covered
+		backup()
+		require.NoError(t, req.Storage.Put(context.Background(), &logical.StorageEntry{Key: "attestations/" + publicKey + "/6", Value: []byte("{}")}))
+		res = writeConfig(map[string]interface{}{"network": "launchtest", "force": true})
+		requireErrorCode(t, res, errorex.CodeBadRequest, "the network of a mount with a wallet can only be changed after a backup of its slashing history")
+
+		backup()
+		res = writeConfig(map[string]interface{}{"network": "launchtest", "force": true})
+		require.Equal(t, core.LaunchTestNetwork, res.Data["network"])
+		require.Equal(t, []string{"the network was changed from test to launchtest, the slashing history of the accounts is kept"}, res.Warnings)
+
+		// the slashing history is kept
+		for _, key := range append(history, "attestations/"+publicKey+"/6") {
+			entry, err := req.Storage.Get(context.Background(), key)
+			require.NoError(t, err)
+			require.NotNil(t, entry, key)
+		}
+	})
+
+	t.Run("Read config history", func(t *testing.T) {
+		historyReq := logical.TestRequest(t, logical.ReadOperation, "config/history")
+		historyReq.Storage = req.Storage
+		res, err := b.HandleRequest(context.Background(), historyReq)
+		require.NoError(t, err)
+
+		changes := res.Data["changes"].([]map[string]interface{})
+		require.Len(t, changes, 3)
+		require.Equal(t, true, changes[0]["forced"])
+		require.Equal(t, core.TestNetwork, changes[0]["previous_network"])
+		require.Equal(t, "admin-accessor", changes[0]["accessor"])
+		require.Equal(t, core.Network(""), changes[2]["previous_network"])
+
+		historyReq.Data = map[string]interface{}{"limit": 1}
+		res, err = b.HandleRequest(context.Background(), historyReq)
+		require.NoError(t, err)
+		require.Len(t, res.Data["changes"], 1)
+	})
+}
+
+func TestConfigHistoryClock(t *testing.T) {
+	b, _ := getBackend(t)
+	now := time.Date(2020, 9, 13, 12, 26, 40, 5, time.UTC)
+	b.(*backend).clock = func() time.Time {
+		return now
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	for i, network := range []string{"test", "launchtest"} {
+		req.ID = fmt.Sprintf("request-%d", i)
+		req.Data = map[string]interface{}{"network": network}
+		_, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	historyReq := logical.TestRequest(t, logical.ReadOperation, "config/history")
+	historyReq.Storage = req.Storage
+	res, err := b.HandleRequest(context.Background(), historyReq)
+	require.NoError(t, err)
+
+	// changes at the same time are all kept
+	changes := res.Data["changes"].([]map[string]interface{})
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		require.Equal(t, "2020-09-13T12:26:40.000000005Z", change["time"])
+	}
+}
@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	ssz "github.com/prysmaticlabs/go-ssz"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// DepositDataPattern is the path pattern for deposit data endpoint
+	DepositDataPattern = "accounts/" + publicKeyRegex + "/deposit-data"
+)
+
+// Deposit parameters
+const (
+	// MaxEffectiveBalanceInGwei is the default deposit amount.
+	MaxEffectiveBalanceInGwei = 32000000000
+
+	// MinDepositAmountInGwei is the minimal deposit amount.
+	MinDepositAmountInGwei = 1000000000
+
+	// depositCLIVersion is the deposit CLI version the launchpad requires deposit data to declare.
+	depositCLIVersion = "1.0.0"
+)
+
+// Withdrawal credentials prefixes
+const (
+	blsWithdrawalPrefixByte     = byte(0x00)
+	eth1AddressWithdrawalPrefix = byte(0x01)
+)
+
+// depositMessage is the SSZ container of the deposit message.
+type depositMessage struct {
+	PublicKey             []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+}
+
+// depositData is the SSZ container of the signed deposit data.
+type depositData struct {
+	PublicKey             []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+	Signature             []byte `ssz-size:"96"`
+}
+
+func depositDataPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         DepositDataPattern,
+			HelpSynopsis:    "Generate deposit data",
+			HelpDescription: `Generate the signed deposit data of the account in the launchpad format`,
+			Fields: map[string]*framework.FieldSchema{
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Public key of the account",
+				},
+				"withdrawal_address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Execution address for 0x01 withdrawal credentials. The account's withdrawal key is used if empty",
+					Default:     "",
+				},
+				"amount": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Deposit amount in Gwei",
+					Default:     MaxEffectiveBalanceInGwei,
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathDepositData,
+			},
+		},
+	}
+}
+
+func (b *backend) pathDepositData(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	// Parse request data
+	publicKey := strings.ToLower(data.Get("public_key").(string))
+	withdrawalAddress := data.Get("withdrawal_address").(string)
+	amount := data.Get("amount").(int)
+
+	if amount < MinDepositAmountInGwei {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("deposit amount is too low"))
+	}
+
+	// bring up KeyVault and wallet
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+
+	// Open wallet, accounts are looked up by the account index
+	if _, err := vault.OpenKeyVault(&options); err != nil {
+		return nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	account, err := storage.AccountByPublicKey(publicKey)
+	if err != nil {
+		if err == wallet_hd.ErrAccountNotFound {
+			return b.notFoundResponse()
+		}
+
+		return nil, errors.Wrap(err, "failed to retrieve account")
+	}
+
+	// Build withdrawal credentials
+	var withdrawalCredentials []byte
+	if len(withdrawalAddress) > 0 {
+		address, err := hex.DecodeString(strings.TrimPrefix(withdrawalAddress, "0x"))
+		if err != nil || len(address) != 20 {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid withdrawal address"))
+		}
+		withdrawalCredentials = eth1AddressWithdrawalCredentials(address)
+	} else if account.WithdrawalPublicKey() != nil {
+		withdrawalCredentials = blsWithdrawalCredentials(account.WithdrawalPublicKey().Marshal())
+	} else {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("account has no withdrawal key, withdrawal address is required"))
+	}
+
+	// Sign deposit message
+	message := &depositMessage{
+		PublicKey:             account.ValidatorPublicKey().Marshal(),
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                uint64(amount),
+	}
+	messageRoot, err := ssz.HashTreeRoot(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine the root hash of deposit message")
+	}
+
+	forkVersion := config.genesisForkVersion()
+	domain := e2types.Domain(e2types.DomainDeposit, forkVersion, e2types.ZeroGenesisValidatorsRoot)
+	root, err := computeSigningRoot(message, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := account.ValidationKeySign(root[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign deposit message")
+	}
+
+	dataRoot, err := ssz.HashTreeRoot(&depositData{
+		PublicKey:             message.PublicKey,
+		WithdrawalCredentials: message.WithdrawalCredentials,
+		Amount:                message.Amount,
+		Signature:             signature.Marshal(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine the root hash of deposit data")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"pubkey":                 hex.EncodeToString(message.PublicKey),
+			"withdrawal_credentials": hex.EncodeToString(message.WithdrawalCredentials),
+			"amount":                 message.Amount,
+			"signature":              hex.EncodeToString(signature.Marshal()),
+			"deposit_message_root":   hex.EncodeToString(messageRoot[:]),
+			"deposit_data_root":      hex.EncodeToString(dataRoot[:]),
+			"fork_version":           hex.EncodeToString(forkVersion),
+			"network_name":           config.launchpadNetworkName(),
+			"deposit_cli_version":    depositCLIVersion,
+		},
+	}, nil
+}
+
+// blsWithdrawalCredentials returns 0x00 withdrawal credentials of the given BLS withdrawal public key.
+func blsWithdrawalCredentials(withdrawalPubKey []byte) []byte {
+	h := sha256.Sum256(withdrawalPubKey)
+	return append([]byte{blsWithdrawalPrefixByte}, h[1:]...)
+}
+
+// eth1AddressWithdrawalCredentials returns 0x01 withdrawal credentials of the given execution address.
+func eth1AddressWithdrawalCredentials(address []byte) []byte {
+	ret := make([]byte, 12, 32)
+	ret[0] = eth1AddressWithdrawalPrefix
+	return append(ret, address...)
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositData(t *testing.T) {
+	b, _ := getBackend(t)
+	publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+
+	t.Run("Successfully Generate Deposit Data with BLS withdrawal credentials", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+
+		// compare with the deposit data generated by the key manager
+		inMemStore, accountID, err := baseInmemStorage()
+		require.NoError(t, err)
+		account, err := inMemStore.OpenAccount(accountID)
+		require.NoError(t, err)
+		account.SetContext(&core.WalletContext{Storage: inMemStore})
+		expected, err := account.GetDepositData()
+		require.NoError(t, err)
+
+		require.Equal(t, publicKey, res.Data["pubkey"])
+		require.EqualValues(t, MaxEffectiveBalanceInGwei, res.Data["amount"])
+		require.Equal(t, expected["withdrawalCredentials"], res.Data["withdrawal_credentials"])
+		require.Equal(t, expected["signature"], res.Data["signature"])
+		require.Equal(t, expected["depositDataRoot"], res.Data["deposit_data_root"])
+		require.Equal(t, "00000003", res.Data["fork_version"])
+		require.Equal(t, "zinken", res.Data["network_name"])
+	})
+
+	t.Run("Network name of the launchpad", func(t *testing.T) {
+		tests := []struct {
+			network     core.Network
+			forkVersion string
+			name        string
+		}{
+			{network: MainnetNetwork, forkVersion: "00000000", name: "mainnet"},
+			{network: PraterNetwork, forkVersion: "00001020", name: "prater"},
+			{network: GoerliNetwork, forkVersion: "00001020", name: "prater"},
+			{network: SepoliaNetwork, forkVersion: "90000069", name: "sepolia"},
+			{network: HoleskyNetwork, forkVersion: "01017000", name: "holesky"},
+			{network: core.TestNetwork, forkVersion: "00000001", name: "medalla"},
+		}
+		for _, test := range tests {
+			t.Run(string(test.network), func(t *testing.T) {
+				req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+				entry, err := logical.StorageEntryJSON("config", Config{
+					Network: test.network,
+				})
+				require.NoError(t, err)
+				require.NoError(t, req.Storage.Put(context.Background(), entry))
+				require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+				res, err := b.HandleRequest(context.Background(), req)
+				require.NoError(t, err)
+				require.Equal(t, test.forkVersion, res.Data["fork_version"])
+				require.Equal(t, test.name, res.Data["network_name"])
+			})
+		}
+	})
+
+	t.Run("Successfully Generate Deposit Data with execution address", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"withdrawal_address": "0x5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c",
+			"amount":             MinDepositAmountInGwei,
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "0100000000000000000000005a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c", res.Data["withdrawal_credentials"])
+		require.EqualValues(t, MinDepositAmountInGwei, res.Data["amount"])
+		require.Len(t, res.Data["signature"], 192)
+	})
+
+	t.Run("Reject invalid withdrawal address", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"withdrawal_address": "0x5a0b",
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject too low amount", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"amount": 1,
+		}
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Deposit Data of unknown account", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey[:95]+"0/deposit-data")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 404, res.Data["http_status_code"])
+	})
+}
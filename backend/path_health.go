@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// Endpoints patterns
+const (
+	// HealthPattern is the path pattern for health endpoint
+	HealthPattern = "health"
+)
+
+func healthPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         HealthPattern,
+			HelpSynopsis:    "Shows whether the mount is ready to sign",
+			HelpDescription: `Checks the storage, the config, the wallet, the accounts and the storage schema of the mount`,
+			Fields:          map[string]*framework.FieldSchema{},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathReadHealth,
+			},
+		},
+	}
+}
+
+// pathReadHealth returns the readiness of the mount.
+// A mount that is not ready is reported with 503 and the reasons it can't sign.
+func (b *backend) pathReadHealth(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var reasons []string
+
+	// The other checks read the storage too, they are skipped if it can't be read
+	if err := storageProbe(ctx, req); err != nil {
+		b.requestLogger(req).Error("Storage probe failed", "error", err)
+		return logical.RespondWithStatusCode(&logical.Response{
+			Data: map[string]interface{}{
+				"ready":   false,
+				"reasons": []string{"storage is not readable"},
+				"storage": false,
+				"version": b.Version,
+			},
+		}, nil, http.StatusServiceUnavailable)
+	}
+
+	config, err := storedConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+	var network core.Network
+	if config == nil {
+		reasons = append(reasons, "config is not written")
+	} else {
+		network = config.Network
+	}
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, network)
+	storage.SetLogger(b.requestLogger(req))
+
+	walletEntry, err := req.Storage.Get(ctx, store.WalletDataPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get wallet")
+	}
+	if walletEntry == nil {
+		reasons = append(reasons, "wallet does not exist")
+	}
+
+	accounts, paused, err := countPausedAccounts(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The mount is halted if every account is paused
+	halted := accounts > 0 && paused == accounts
+	switch {
+	case accounts == 0:
+		reasons = append(reasons, "wallet has no accounts")
+	case halted:
+		reasons = append(reasons, "all accounts are paused")
+	}
+
+	schemaVersion, err := storage.SchemaVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get schema version")
+	}
+	if schemaVersion < store.CurrentSchemaVersion {
+		reasons = append(reasons, "storage migrations are pending")
+	}
+
+	responseData := map[string]interface{}{
+		"ready":                  len(reasons) == 0,
+		"reasons":                reasons,
+		"network":                network,
+		"wallet":                 walletEntry != nil,
+		"accounts":               accounts,
+		"paused_accounts":        paused,
+		"halted":                 halted,
+		"schema_version":         schemaVersion,
+		"current_schema_version": store.CurrentSchemaVersion,
+		"storage":                true,
+		"version":                b.Version,
+	}
+
+	if len(reasons) > 0 {
+		return logical.RespondWithStatusCode(&logical.Response{
+			Data: responseData,
+		}, nil, http.StatusServiceUnavailable)
+	}
+
+	return &logical.Response{
+		Data: responseData,
+	}, nil
+}
+
+// countPausedAccounts returns the number of accounts and the number of paused accounts.
+func countPausedAccounts(storage *store.HashicorpVaultStore) (int, int, error) {
+	publicKeys, err := storage.ListAccountPublicKeys()
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to list accounts")
+	}
+
+	paused := 0
+	for _, publicKey := range publicKeys {
+		metadata, err := storage.OpenAccountMetadata(publicKey)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to open account metadata")
+		}
+		if metadata.Status == store.AccountStatusPaused {
+			paused++
+		}
+	}
+
+	return len(publicKeys), paused, nil
+}
+
+// storageProbe reads the schema version record. Health is read on standby nodes too,
+// so the probe doesn't write.
+func storageProbe(ctx context.Context, req *logical.Request) error {
+	_, err := req.Storage.Get(ctx, store.SchemaVersionPath)
+	return errors.Wrap(err, "failed to read schema version")
+}
@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestHealth(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "health")
+	health := func(t *testing.T) *logical.Response {
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Not ready without config and wallet", func(t *testing.T) {
+		res := health(t)
+		require.EqualValues(t, 503, res.Data["http_status_code"])
+		require.Contains(t, res.Data["http_raw_body"], "config is not written")
+		require.Contains(t, res.Data["http_raw_body"], "wallet does not exist")
+	})
+
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+	migrateReq := logical.TestRequest(t, logical.CreateOperation, "storage/migration")
+	migrateReq.Storage = req.Storage
+	_, err := b.HandleRequest(context.Background(), migrateReq)
+	require.NoError(t, err)
+
+	t.Run("Ready with wallet and accounts", func(t *testing.T) {
+		res := health(t)
+		require.Nil(t, res.Data["http_status_code"])
+		require.Equal(t, true, res.Data["ready"])
+		require.Equal(t, true, res.Data["wallet"])
+		require.Equal(t, true, res.Data["storage"])
+		require.Equal(t, false, res.Data["halted"])
+		require.EqualValues(t, store.CurrentSchemaVersion, res.Data["schema_version"])
+		require.NotZero(t, res.Data["accounts"])
+	})
+
+	t.Run("Ready on read-only storage", func(t *testing.T) {
+		readOnlyReq := logical.TestRequest(t, logical.ReadOperation, "health")
+		readOnlyReq.Storage = &readOnlyStorage{Storage: req.Storage}
+		res, err := b.HandleRequest(context.Background(), readOnlyReq)
+		require.NoError(t, err)
+		require.Equal(t, true, res.Data["ready"])
+	})
+
+	t.Run("Not ready if storage can't be read", func(t *testing.T) {
+		failingReq := logical.TestRequest(t, logical.ReadOperation, "health")
+		failingReq.Storage = &failingStorage{Storage: req.Storage}
+		res, err := b.HandleRequest(context.Background(), failingReq)
+		require.NoError(t, err)
+		require.EqualValues(t, 503, res.Data["http_status_code"])
+		require.Contains(t, res.Data["http_raw_body"], "storage is not readable")
+		require.Contains(t, res.Data["http_raw_body"], `"storage":false`)
+	})
+
+	t.Run("Halted when all accounts are paused", func(t *testing.T) {
+		publicKeys, err := store.NewHashicorpVaultStore(context.Background(), req.Storage, "").ListAccountPublicKeys()
+		require.NoError(t, err)
+		for _, publicKey := range publicKeys {
+			pauseReq := logical.TestRequest(t, logical.UpdateOperation, "accounts/"+publicKey+"/metadata")
+			pauseReq.Storage = req.Storage
+			pauseReq.Data = map[string]interface{}{
+				"status": "paused",
+			}
+			_, err := b.HandleRequest(context.Background(), pauseReq)
+			require.NoError(t, err)
+		}
+
+		res := health(t)
+		require.EqualValues(t, 503, res.Data["http_status_code"])
+		require.Contains(t, res.Data["http_raw_body"], `"halted":true`)
+		require.Contains(t, res.Data["http_raw_body"], "all accounts are paused")
+	})
+}
+
+// readOnlyStorage fails every write, as the storage of a standby node.
+type readOnlyStorage struct {
+	logical.Storage
+}
+
+func (s *readOnlyStorage) Put(context.Context, *logical.StorageEntry) error {
+	return logical.ErrReadOnly
+}
+
+func (s *readOnlyStorage) Delete(context.Context, string) error {
+	return logical.ErrReadOnly
+}
+
+// failingStorage fails every read.
+type failingStorage struct {
+	logical.Storage
+}
+
+func (s *failingStorage) Get(context.Context, string) (*logical.StorageEntry, error) {
+	return nil, errors.New("storage unavailable")
+}
@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Endpoints patterns
+const (
+	// MetricsPattern is the path pattern for metrics endpoint
+	MetricsPattern = "metrics"
+)
+
+func metricsPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         MetricsPattern,
+			HelpSynopsis:    "Read the metrics",
+			HelpDescription: `Read the signing and storage metrics of the mount in the Prometheus text format`,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathReadMetrics,
+			},
+		},
+	}
+}
+
+func (b *backend) pathReadMetrics(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	families, err := b.metrics.registry.Gather()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to gather metrics")
+	}
+
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return nil, errors.Wrap(err, "failed to encode metrics")
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: string(expfmt.FmtText),
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	req.Data = basicAttestationData()
+	_, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	unknown := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	unknown.Storage = req.Storage
+	unknown.Data = basicAttestationData()
+	unknown.Data["public_key"] = "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3270"
+	_, err = b.HandleRequest(context.Background(), unknown)
+	require.NoError(t, err)
+
+	metricsReq := logical.TestRequest(t, logical.ReadOperation, "metrics")
+	metricsReq.Storage = req.Storage
+	res, err := b.HandleRequest(context.Background(), metricsReq)
+	require.NoError(t, err)
+	require.EqualValues(t, 200, res.Data[logical.HTTPStatusCode])
+	require.Contains(t, res.Data[logical.HTTPContentType], "text/plain")
+
+	body := string(res.Data[logical.HTTPRawBody].([]byte))
+	require.Contains(t, body, `ethsign_sign_requests_total{operation="attestation",outcome="signed"} 1`)
+	require.Contains(t, body, `ethsign_sign_requests_total{operation="attestation",outcome="not_found"} 1`)
+	require.Contains(t, body, `ethsign_sign_duration_seconds_count{operation="attestation",outcome="signed"} 1`)
+	require.Contains(t, body, `ethsign_storage_operation_duration_seconds_count{operation="get"}`)
+	require.Contains(t, body, `ethsign_storage_operation_duration_seconds_count{operation="put"}`)
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 func ignoreError(val interface{}, err error) interface{} {
@@ -91,9 +93,8 @@ func TestAttestationSlashing(t *testing.T) {
 		data["beaconBlockRoot"] = "7b5679277ca45ea74e1deebc9d3e8c0e7d6c570b3cfaf6884be144a81dac9a0f"
 		req.Data = data
 		res, err = b.HandleRequest(context.Background(), req)
-		require.Error(t, err)
-		require.EqualError(t, err, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
-		require.Nil(t, res)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeSlashableDoubleVote, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
 	})
 
 	t.Run("Sign double Attestation (different source root), should return error", func(t *testing.T) {
@@ -115,9 +116,8 @@ func TestAttestationSlashing(t *testing.T) {
 		data["sourceRoot"] = "7402fdc1ce16d449d637c34a172b349a12b2bae8d6d77e401006594d8057c33e"
 		req.Data = data
 		res, err = b.HandleRequest(context.Background(), req)
-		require.Error(t, err)
-		require.EqualError(t, err, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
-		require.Nil(t, res)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeSlashableDoubleVote, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
 	})
 
 	t.Run("Sign double Attestation (different target root), should return error", func(t *testing.T) {
@@ -139,9 +139,8 @@ func TestAttestationSlashing(t *testing.T) {
 		data["targetRoot"] = "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb1"
 		req.Data = data
 		res, err = b.HandleRequest(context.Background(), req)
-		require.Error(t, err)
-		require.EqualError(t, err, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
-		require.Nil(t, res)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeSlashableDoubleVote, "failed to sign attestation: slashable attestation (DoubleVote), not signing")
 	})
 
 	t.Run("Sign Attestation (different domain), should sign", func(t *testing.T) {
@@ -216,9 +215,8 @@ func TestAttestationSlashing(t *testing.T) {
 			"targetRoot":      "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0",
 		}
 		res, err := b.HandleRequest(context.Background(), req)
-		require.Error(t, err)
-		require.EqualError(t, err, "failed to sign attestation: slashable attestation (SurroundingVote), not signing")
-		require.Nil(t, res)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeSlashableSurround, "failed to sign attestation: slashable attestation (SurroundingVote), not signing")
 	})
 
 	t.Run("Sign surrounded Attestation, should error", func(t *testing.T) {
@@ -266,8 +264,7 @@ func TestAttestationSlashing(t *testing.T) {
 			"targetRoot":      "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0",
 		}
 		res, err := b.HandleRequest(context.Background(), req)
-		require.Error(t, err)
-		require.EqualError(t, err, "failed to sign attestation: slashable attestation (SurroundedVote), not signing")
-		require.Nil(t, res)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeSlashableSurround, "failed to sign attestation: slashable attestation (SurroundedVote), not signing")
 	})
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 func basicProposalData() map[string]interface{} {
@@ -89,9 +91,9 @@ func TestProposalSlashing(t *testing.T) {
 		// second proposal
 		req.Data = basicProposalData()
 		req.Data["stateRoot"] = "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb1"
-		_, err = b.HandleRequest(context.Background(), req)
-		require.NotNil(t, err)
-		require.EqualError(t, err, "failed to sign data: err, slashable proposal: DoubleProposal")
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeDoubleProposal, "failed to sign data: err, slashable proposal: DoubleProposal")
 	})
 
 	t.Run("Sign double proposal(different parent root), should error", func(t *testing.T) {
@@ -110,9 +112,9 @@ func TestProposalSlashing(t *testing.T) {
 		// second proposal
 		req.Data = basicProposalData()
 		req.Data["parentRoot"] = "7402fdc1ce16d449d637c34a172b349a12b2bae8d6d77e401006594d8057c33e"
-		_, err = b.HandleRequest(context.Background(), req)
-		require.NotNil(t, err)
-		require.EqualError(t, err, "failed to sign data: err, slashable proposal: DoubleProposal")
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeDoubleProposal, "failed to sign data: err, slashable proposal: DoubleProposal")
 	})
 
 	t.Run("Sign double proposal(different body root), should error", func(t *testing.T) {
@@ -131,8 +133,8 @@ func TestProposalSlashing(t *testing.T) {
 		// second proposal
 		req.Data = basicProposalData()
 		req.Data["bodyRoot"] = "7b5679277ca45ea74e1deebc9d3e8c0e7d6c570b3cfaf6884be144a81dac9a0d"
-		_, err = b.HandleRequest(context.Background(), req)
-		require.NotNil(t, err)
-		require.EqualError(t, err, "failed to sign data: err, slashable proposal: DoubleProposal")
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeDoubleProposal, "failed to sign data: err, slashable proposal: DoubleProposal")
 	})
 }
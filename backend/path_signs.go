@@ -3,8 +3,11 @@ package backend
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
+	"strings"
 
 	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/core"
 	"github.com/bloxapp/eth2-key-manager/slashing_protection"
 	"github.com/bloxapp/eth2-key-manager/validator_signer"
 	"github.com/bloxapp/eth2-key-manager/wallet_hd"
@@ -14,6 +17,7 @@ import (
 	v1 "github.com/wealdtech/eth2-signer-api/pb/v1"
 
 	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // Endpoints patterns
@@ -88,7 +92,7 @@ func signsPaths(b *backend) []*framework.Path {
 			},
 			ExistenceCheck: b.pathExistenceCheck,
 			Callbacks: map[logical.Operation]framework.OperationFunc{
-				logical.CreateOperation: b.pathSignAttestation,
+				logical.CreateOperation: b.recorded(store.AuditMessageAttestation, b.pathSignAttestation),
 			},
 		},
 		&framework.Path{
@@ -139,7 +143,7 @@ func signsPaths(b *backend) []*framework.Path {
 			},
 			ExistenceCheck: b.pathExistenceCheck,
 			Callbacks: map[logical.Operation]framework.OperationFunc{
-				logical.CreateOperation: b.pathSignProposal,
+				logical.CreateOperation: b.recorded(store.AuditMessageProposal, b.pathSignProposal),
 			},
 		},
 		&framework.Path{
@@ -170,55 +174,51 @@ func signsPaths(b *backend) []*framework.Path {
 			},
 			ExistenceCheck: b.pathExistenceCheck,
 			Callbacks: map[logical.Operation]framework.OperationFunc{
-				logical.CreateOperation: b.pathSignAggregation,
+				logical.CreateOperation: b.recorded(store.AuditMessageAggregation, b.pathSignAggregation),
 			},
 		},
 	}
 }
 
 func (b *backend) pathSignAttestation(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Load config
-	config, err := b.configured(ctx, req)
+	// bring up the store
+	config, storage, err := b.cachedStore(ctx, req)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get config")
+		return nil, err
 	}
 
-	// bring up KeyVault and wallet
-	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
-	options := vault.KeyVaultOptions{}
-	options.SetStorage(storage)
-
 	// Parse request data
 	publicKey := data.Get("public_key").(string)
-	domain := data.Get("domain").(string)
 	slot := data.Get("slot").(int)
-	committeeIndex := data.Get("committeeIndex").(int)
-	beaconBlockRoot := data.Get("beaconBlockRoot").(string)
 	sourceEpoch := data.Get("sourceEpoch").(int)
-	sourceRoot := data.Get("sourceRoot").(string)
 	targetEpoch := data.Get("targetEpoch").(int)
-	targetRoot := data.Get("targetRoot").(string)
-
-	// Open wallet
-	kv, err := vault.OpenKeyVault(&options)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open key vault")
-	}
-
-	wallet, err := kv.Wallet()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve wallet")
-	}
 
-	account, err := wallet.AccountByPublicKey(publicKey)
+	account, err := storage.AccountByPublicKey(publicKey)
 	if err != nil {
 		if err == wallet_hd.ErrAccountNotFound {
-			return b.notFoundResponse()
+			return b.signAccountNotFound(storage)
 		}
 
 		return nil, errors.Wrap(err, "failed to retrieve account")
 	}
 
+	// Paused accounts don't sign
+	if res, err := b.checkAccountActive(storage, publicKey); res != nil || err != nil {
+		return res, err
+	}
+
+	// Refuse slots far from the wall-clock slot
+	if res, err := b.checkSlotWindow(config, uint64(slot)); res != nil || err != nil {
+		return res, err
+	}
+	if res, err := b.checkEpochWindow(config, uint64(sourceEpoch), uint64(targetEpoch)); res != nil || err != nil {
+		return res, err
+	}
+
+	// Backups and restores wait for the signature to be recorded
+	b.signLock.RLock()
+	defer b.signLock.RUnlock()
+
 	// try to lock signature lock, if it fails return error
 	lock := NewDBLock(account.ID(), req.Storage)
 	if err := lock.Lock(); err != nil {
@@ -226,56 +226,22 @@ func (b *backend) pathSignAttestation(ctx context.Context, req *logical.Request,
 	}
 	defer lock.UnLock()
 
-	// Decode public key
-	publicKeyBytes, err := hex.DecodeString(publicKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode public key")
-	}
-
-	// Decode domain
-	domainBytes, err := hex.DecodeString(domain)
+	attestationRequest, err := signAttestationRequest(data)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode domain")
+		return b.prepareErrorResponse(err)
 	}
-
-	// Decode beacon block root
-	beaconBlockRootBytes, err := hex.DecodeString(beaconBlockRoot)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode beacon block root")
+	if !config.ValidDomain(attestationRequest.GetDomain()) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("domain is not of the configured network"))
 	}
 
-	// Decode source root
-	sourceRootBytes, err := hex.DecodeString(sourceRoot)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode source root")
-	}
+	protector := slashing_protection.NewNormalProtection(storage)
+	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(newAccountWallet(account), protector)
 
-	// Decode target root
-	targetRootBytes, err := hex.DecodeString(targetRoot)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode target root")
+	if err := slashableAttestation(protector, account, attestationRequest); err != nil {
+		return nil, errors.Wrap(err, "failed to sign attestation")
 	}
 
-	protector := slashing_protection.NewNormalProtection(storage)
-	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(wallet, protector)
-
-	res, err := signer.SignBeaconAttestation(&v1.SignBeaconAttestationRequest{
-		Id:     &v1.SignBeaconAttestationRequest_PublicKey{PublicKey: publicKeyBytes},
-		Domain: domainBytes,
-		Data: &v1.AttestationData{
-			Slot:            uint64(slot),
-			CommitteeIndex:  uint64(committeeIndex),
-			BeaconBlockRoot: beaconBlockRootBytes,
-			Source: &v1.Checkpoint{
-				Epoch: uint64(sourceEpoch),
-				Root:  sourceRootBytes,
-			},
-			Target: &v1.Checkpoint{
-				Epoch: uint64(targetEpoch),
-				Root:  targetRootBytes,
-			},
-		},
-	})
+	res, err := signer.SignBeaconAttestation(attestationRequest)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to sign attestation")
 	}
@@ -288,46 +254,39 @@ func (b *backend) pathSignAttestation(ctx context.Context, req *logical.Request,
 }
 
 func (b *backend) pathSignProposal(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Load config
-	config, err := b.configured(ctx, req)
+	// bring up the store
+	config, storage, err := b.cachedStore(ctx, req)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get config")
+		return nil, err
 	}
 
-	// bring up KeyVault and wallet
-	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
-	options := vault.KeyVaultOptions{}
-	options.SetStorage(storage)
-
 	// Parse request data
 	publicKey := data.Get("public_key").(string)
-	domain := data.Get("domain").(string)
 	slot := data.Get("slot").(int)
-	proposerIndex := data.Get("proposerIndex").(int)
-	parentRoot := data.Get("parentRoot").(string)
-	stateRoot := data.Get("stateRoot").(string)
-	bodyRoot := data.Get("bodyRoot").(string)
 
-	// Open wallet
-	kv, err := vault.OpenKeyVault(&options)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open key vault")
-	}
-
-	wallet, err := kv.Wallet()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve wallet by name")
-	}
-
-	account, err := wallet.AccountByPublicKey(publicKey)
+	account, err := storage.AccountByPublicKey(publicKey)
 	if err != nil {
 		if err == wallet_hd.ErrAccountNotFound {
-			return b.notFoundResponse()
+			return b.signAccountNotFound(storage)
 		}
 
 		return nil, errors.Wrap(err, "failed to retrieve account")
 	}
 
+	// Paused accounts don't sign
+	if res, err := b.checkAccountActive(storage, publicKey); res != nil || err != nil {
+		return res, err
+	}
+
+	// Refuse slots far from the wall-clock slot
+	if res, err := b.checkSlotWindow(config, uint64(slot)); res != nil || err != nil {
+		return res, err
+	}
+
+	// Backups and restores wait for the signature to be recorded
+	b.signLock.RLock()
+	defer b.signLock.RUnlock()
+
 	// try to lock signature lock, if it fails return error
 	lock := NewDBLock(account.ID(), req.Storage)
 	if err := lock.Lock(); err != nil {
@@ -335,52 +294,80 @@ func (b *backend) pathSignProposal(ctx context.Context, req *logical.Request, da
 	}
 	defer lock.UnLock()
 
-	// Decode public key
-	publicKeyBytes, err := hex.DecodeString(publicKey)
+	proposalRequest, err := signProposalRequest(data)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode public key")
+		return b.prepareErrorResponse(err)
+	}
+	if !config.ValidDomain(proposalRequest.GetDomain()) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("domain is not of the configured network"))
 	}
 
-	// Decode domain
-	domainBytes, err := hex.DecodeString(domain)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode domain")
+	protector := slashing_protection.NewNormalProtection(storage)
+	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(newAccountWallet(account), protector)
+
+	if err := slashableProposal(protector, account, proposalRequest); err != nil {
+		return nil, errors.Wrap(err, "failed to sign data")
 	}
 
-	// Decode parent root
-	parentRootBytes, err := hex.DecodeString(parentRoot)
+	res, err := signer.SignBeaconProposal(proposalRequest)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode parent root")
+		return nil, errors.Wrap(err, "failed to sign data")
 	}
 
-	// Decode state root
-	stateRootBytes, err := hex.DecodeString(stateRoot)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"signature": hex.EncodeToString(res.GetSignature()),
+		},
+	}, nil
+}
+
+func (b *backend) pathSignAggregation(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// bring up the store
+	config, storage, err := b.cachedStore(ctx, req)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode state root")
+		return nil, err
 	}
 
-	// Decode body root
-	bodyRootBytes, err := hex.DecodeString(bodyRoot)
+	// Parse request data
+	publicKey := data.Get("public_key").(string)
+
+	account, err := storage.AccountByPublicKey(publicKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode body root")
+		if err == wallet_hd.ErrAccountNotFound {
+			return b.signAccountNotFound(storage)
+		}
+
+		return nil, errors.Wrap(err, "failed to retrieve account")
 	}
 
-	proposalRequest := &v1.SignBeaconProposalRequest{
-		Id:     &v1.SignBeaconProposalRequest_PublicKey{PublicKey: publicKeyBytes},
-		Domain: domainBytes,
-		Data: &v1.BeaconBlockHeader{
-			Slot:          uint64(slot),
-			ProposerIndex: uint64(proposerIndex),
-			ParentRoot:    parentRootBytes,
-			StateRoot:     stateRootBytes,
-			BodyRoot:      bodyRootBytes,
-		},
+	// Paused accounts don't sign
+	if res, err := b.checkAccountActive(storage, publicKey); res != nil || err != nil {
+		return res, err
+	}
+
+	// Backups and restores wait for the signature to be recorded
+	b.signLock.RLock()
+	defer b.signLock.RUnlock()
+
+	// try to lock signature lock, if it fails return error
+	lock := NewDBLock(account.ID(), req.Storage)
+	if err := lock.Lock(); err != nil {
+		return nil, err
+	}
+	defer lock.UnLock()
+
+	signRequest, err := signAggregationRequest(data)
+	if err != nil {
+		return b.prepareErrorResponse(err)
+	}
+	if !config.ValidDomain(signRequest.GetDomain()) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("domain is not of the configured network"))
 	}
 
 	protector := slashing_protection.NewNormalProtection(storage)
-	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(wallet, protector)
+	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(newAccountWallet(account), protector)
 
-	res, err := signer.SignBeaconProposal(proposalRequest)
+	res, err := signer.Sign(signRequest)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to sign data")
 	}
@@ -392,85 +379,180 @@ func (b *backend) pathSignProposal(ctx context.Context, req *logical.Request, da
 	}, nil
 }
 
-func (b *backend) pathSignAggregation(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Load config
-	config, err := b.configured(ctx, req)
+// signAttestationRequest decodes the attestation signing request of the given request data.
+// Invalid fields are returned as bad request errors.
+func signAttestationRequest(data *framework.FieldData) (*v1.SignBeaconAttestationRequest, error) {
+	publicKey, err := hex.DecodeString(data.Get("public_key").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get config")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode public key")
 	}
 
-	// bring up KeyVault and wallet
-	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
-	options := vault.KeyVaultOptions{}
-	options.SetStorage(storage)
-
-	// Parse request data
-	publicKey := data.Get("public_key").(string)
-	domain := data.Get("domain").(string)
-	dataToSign := data.Get("dataToSign").(string)
+	domain, err := hex.DecodeString(data.Get("domain").(string))
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode domain")
+	}
 
-	// Open wallet
-	kv, err := vault.OpenKeyVault(&options)
+	beaconBlockRoot, err := hex.DecodeString(data.Get("beaconBlockRoot").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to open key vault")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode beacon block root")
 	}
 
-	wallet, err := kv.Wallet()
+	sourceRoot, err := hex.DecodeString(data.Get("sourceRoot").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve wallet by name")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode source root")
 	}
 
-	account, err := wallet.AccountByPublicKey(publicKey)
+	targetRoot, err := hex.DecodeString(data.Get("targetRoot").(string))
 	if err != nil {
-		if err == wallet_hd.ErrAccountNotFound {
-			return b.notFoundResponse()
-		}
+		return nil, errorex.NewErrBadRequest("failed to HEX decode target root")
+	}
 
-		return nil, errors.Wrap(err, "failed to retrieve account")
+	return &v1.SignBeaconAttestationRequest{
+		Id:     &v1.SignBeaconAttestationRequest_PublicKey{PublicKey: publicKey},
+		Domain: domain,
+		Data: &v1.AttestationData{
+			Slot:            uint64(data.Get("slot").(int)),
+			CommitteeIndex:  uint64(data.Get("committeeIndex").(int)),
+			BeaconBlockRoot: beaconBlockRoot,
+			Source: &v1.Checkpoint{
+				Epoch: uint64(data.Get("sourceEpoch").(int)),
+				Root:  sourceRoot,
+			},
+			Target: &v1.Checkpoint{
+				Epoch: uint64(data.Get("targetEpoch").(int)),
+				Root:  targetRoot,
+			},
+		},
+	}, nil
+}
+
+// signProposalRequest decodes the proposal signing request of the given request data.
+// Invalid fields are returned as bad request errors.
+func signProposalRequest(data *framework.FieldData) (*v1.SignBeaconProposalRequest, error) {
+	publicKey, err := hex.DecodeString(data.Get("public_key").(string))
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode public key")
 	}
 
-	// try to lock signature lock, if it fails return error
-	lock := NewDBLock(account.ID(), req.Storage)
-	if err := lock.Lock(); err != nil {
-		return nil, err
+	domain, err := hex.DecodeString(data.Get("domain").(string))
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode domain")
 	}
-	defer lock.UnLock()
 
-	// Decode public key
-	publicKeyBytes, err := hex.DecodeString(publicKey)
+	parentRoot, err := hex.DecodeString(data.Get("parentRoot").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode public key")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode parent root")
 	}
 
-	// Decode domain
-	domainBytes, err := hex.DecodeString(domain)
+	stateRoot, err := hex.DecodeString(data.Get("stateRoot").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode domain")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode state root")
 	}
 
-	// Decode data to sign
-	dataToSignBytes, err := hex.DecodeString(dataToSign)
+	bodyRoot, err := hex.DecodeString(data.Get("bodyRoot").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode data to sign")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode body root")
 	}
 
-	proposalRequest := &v1.SignRequest{
-		Id:     &v1.SignRequest_PublicKey{PublicKey: publicKeyBytes},
-		Domain: domainBytes,
-		Data:   dataToSignBytes,
+	return &v1.SignBeaconProposalRequest{
+		Id:     &v1.SignBeaconProposalRequest_PublicKey{PublicKey: publicKey},
+		Domain: domain,
+		Data: &v1.BeaconBlockHeader{
+			Slot:          uint64(data.Get("slot").(int)),
+			ProposerIndex: uint64(data.Get("proposerIndex").(int)),
+			ParentRoot:    parentRoot,
+			StateRoot:     stateRoot,
+			BodyRoot:      bodyRoot,
+		},
+	}, nil
+}
+
+// signAggregationRequest decodes the signing request of the given request data.
+// Invalid fields are returned as bad request errors.
+func signAggregationRequest(data *framework.FieldData) (*v1.SignRequest, error) {
+	publicKey, err := hex.DecodeString(data.Get("public_key").(string))
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode public key")
 	}
 
-	protector := slashing_protection.NewNormalProtection(storage)
-	var signer validator_signer.ValidatorSigner = validator_signer.NewSimpleSigner(wallet, protector)
+	domain, err := hex.DecodeString(data.Get("domain").(string))
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode domain")
+	}
 
-	res, err := signer.Sign(proposalRequest)
+	dataToSign, err := hex.DecodeString(data.Get("dataToSign").(string))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to sign data")
+		return nil, errorex.NewErrBadRequest("failed to HEX decode data to sign")
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"signature": hex.EncodeToString(res.GetSignature()),
-		},
+	return &v1.SignRequest{
+		Id:     &v1.SignRequest_PublicKey{PublicKey: publicKey},
+		Domain: domain,
+		Data:   dataToSign,
 	}, nil
 }
+
+// slashableAttestation returns the coded error of an attestation refused by slashing protection.
+// The message is the one the signer refuses the attestation with.
+func slashableAttestation(protector core.SlashingProtector, account core.ValidatorAccount, req *v1.SignBeaconAttestationRequest) error {
+	statuses, err := protector.IsSlashableAttestation(account.ValidatorPublicKey(), req)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("slashable attestation (%s), not signing", statuses[0].Status)
+	switch statuses[0].Status {
+	case core.DoubleVote:
+		return errorex.NewCodedError(errorex.CodeSlashableDoubleVote, message)
+	case core.SurroundingVote, core.SurroundedVote:
+		return errorex.NewCodedError(errorex.CodeSlashableSurround, message)
+	default:
+		return errors.New(message)
+	}
+}
+
+// slashableProposal returns the coded error of a proposal refused by slashing protection.
+// The message is the one the signer refuses the proposal with.
+func slashableProposal(protector core.SlashingProtector, account core.ValidatorAccount, req *v1.SignBeaconProposalRequest) error {
+	status := protector.IsSlashableProposal(account.ValidatorPublicKey(), req)
+	switch status.Status {
+	case core.ValidProposal:
+		return nil
+	case core.DoubleProposal:
+		return errorex.NewCodedError(errorex.CodeDoubleProposal, fmt.Sprintf("err, slashable proposal: %s", status.Status))
+	default:
+		if status.Error != nil {
+			return status.Error
+		}
+		return errors.Errorf("err, slashable proposal: %s", status.Status)
+	}
+}
+
+// signAccountNotFound returns the response of a signing request for an account that isn't indexed.
+// The wallet is only opened then, to tell a mount without a wallet from an unknown account.
+func (b *backend) signAccountNotFound(storage *store.HashicorpVaultStore) (*logical.Response, error) {
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+	if _, err := vault.OpenKeyVault(&options); err != nil {
+		return nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	return b.notFoundResponse()
+}
+
+// checkAccountActive returns an error response if the account is paused.
+func (b *backend) checkAccountActive(storage *store.HashicorpVaultStore, publicKey string) (*logical.Response, error) {
+	metadata, err := storage.OpenAccountMetadata(strings.ToLower(publicKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open account metadata")
+	}
+
+	if metadata.Status == store.AccountStatusPaused {
+		return b.prepareErrorResponse(errorex.NewCodedError(errorex.CodeHalted, "account is paused"))
+	}
+
+	return nil, nil
+}
@@ -2,10 +2,20 @@ package backend
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"testing"
 
+	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/slashing_protection"
+	"github.com/bloxapp/eth2-key-manager/validator_signer"
+	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
+	v1 "github.com/wealdtech/eth2-signer-api/pb/v1"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 func setupStorageWithWalletAndAccounts(storage logical.Storage) error {
@@ -13,6 +23,24 @@ func setupStorageWithWalletAndAccounts(storage logical.Storage) error {
 	return err
 }
 
+// requireErrorCode asserts the response is an error with the given code and message.
+func requireErrorCode(t *testing.T, res *logical.Response, code errorex.ErrorCode, message string) {
+	require.NotNil(t, res)
+	require.EqualValues(t, code.StatusCode(), res.Data["http_status_code"])
+
+	var body struct {
+		Data struct {
+			Code    errorex.ErrorCode `json:"code"`
+			Message string            `json:"message"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Data["http_raw_body"].(string)), &body))
+	require.Equal(t, code, body.Data.Code)
+	if len(message) > 0 {
+		require.Equal(t, message, body.Data.Message)
+	}
+}
+
 func TestSignAttestation(t *testing.T) {
 	b, _ := getBackend(t)
 
@@ -56,6 +84,20 @@ func TestSignAttestation(t *testing.T) {
 		require.NoError(t, err)
 		require.EqualValues(t, 404, resp.Data["http_status_code"], resp.Data)
 	})
+
+	t.Run("Reject invalid HEX domain", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		req.Data = map[string]interface{}{
+			"public_key": "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279",
+			"domain":     "0xzz",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeBadRequest, "failed to HEX decode domain")
+	})
 }
 
 func TestSignProposal(t *testing.T) {
@@ -101,6 +143,20 @@ func TestSignProposal(t *testing.T) {
 		require.NoError(t, err)
 		require.EqualValues(t, 404, resp.Data["http_status_code"], resp.Data)
 	})
+
+	t.Run("Reject invalid HEX bodyRoot", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-proposal")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		req.Data = map[string]interface{}{
+			"public_key": "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279",
+			"bodyRoot":   "0xzz",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeBadRequest, "failed to HEX decode body root")
+	})
 }
 
 func TestSignAggregation(t *testing.T) {
@@ -145,4 +201,188 @@ func TestSignAggregation(t *testing.T) {
 		require.NoError(t, err)
 		require.EqualValues(t, 404, resp.Data["http_status_code"], resp.Data)
 	})
+
+	t.Run("Reject invalid HEX dataToSign", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-aggregation")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		req.Data = map[string]interface{}{
+			"public_key": "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279",
+			"dataToSign": "0xzz",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeBadRequest, "failed to HEX decode data to sign")
+	})
+}
+
+func TestSignPausedAccount(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "accounts/ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279/metadata")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+	req.Data = map[string]interface{}{
+		"status": "paused",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	signReq := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	signReq.Storage = req.Storage
+	signReq.Data = basicAttestationData()
+	res, err := b.HandleRequest(context.Background(), signReq)
+	require.NoError(t, err)
+	requireErrorCode(t, res, errorex.CodeHalted, "account is paused")
+
+	// active accounts sign again
+	req.Data = map[string]interface{}{
+		"status": "active",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	res, err = b.HandleRequest(context.Background(), signReq)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Data["signature"])
+}
+
+func TestSignNetworkDomain(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Data = map[string]interface{}{
+		"network": "mainnet",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	signAggregation := func(domain []byte) *logical.Response {
+		signReq := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-aggregation")
+		signReq.Storage = req.Storage
+		signReq.Data = map[string]interface{}{
+			"public_key": "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279",
+			"domain":     hex.EncodeToString(domain),
+			"dataToSign": "01020304",
+		}
+		res, err := b.HandleRequest(context.Background(), signReq)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Sign with a domain of the configured network", func(t *testing.T) {
+		domain := e2types.Domain(e2types.DomainBeaconAttester, []byte{0x03, 0x00, 0x00, 0x00}, _byteArray("4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"))
+		res := signAggregation(domain)
+		require.NotEmpty(t, res.Data["signature"], res.Data)
+	})
+
+	t.Run("Reject a domain of another network", func(t *testing.T) {
+		domain := e2types.Domain(e2types.DomainBeaconAttester, []byte{0x03, 0x00, 0x10, 0x20}, _byteArray("043db0d9a83813551ee2f33450d23797757d430911a9320530ad8a0eabc43efb"))
+		res := signAggregation(domain)
+		require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+	})
+}
+
+func TestSlashableErrors(t *testing.T) {
+	b, _ := getBackend(t)
+	paths := signsPaths(b.(*backend))
+
+	req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	storage, err := baseHashicorpStorage(req.Storage, context.Background())
+	require.NoError(t, err)
+
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+	kv, err := vault.OpenKeyVault(&options)
+	require.NoError(t, err)
+	wallet, err := kv.Wallet()
+	require.NoError(t, err)
+	account, err := storage.AccountByPublicKey(basicAttestationData()["public_key"].(string))
+	require.NoError(t, err)
+
+	protector := slashing_protection.NewNormalProtection(storage)
+	signer := validator_signer.NewSimpleSigner(wallet, protector)
+
+	attestation := func(changes map[string]interface{}) *v1.SignBeaconAttestationRequest {
+		data := basicAttestationData()
+		for key, value := range changes {
+			data[key] = value
+		}
+		attestationRequest, err := signAttestationRequest(&framework.FieldData{Raw: data, Schema: paths[0].Fields})
+		require.NoError(t, err)
+		return attestationRequest
+	}
+	proposal := func(changes map[string]interface{}) *v1.SignBeaconProposalRequest {
+		data := basicProposalData()
+		for key, value := range changes {
+			data[key] = value
+		}
+		proposalRequest, err := signProposalRequest(&framework.FieldData{Raw: data, Schema: paths[1].Fields})
+		require.NoError(t, err)
+		return proposalRequest
+	}
+
+	// 8877 <- 8878 and 8878 <----- 9000
+	_, err = signer.SignBeaconAttestation(attestation(nil))
+	require.NoError(t, err)
+	_, err = signer.SignBeaconAttestation(attestation(map[string]interface{}{"slot": 284116, "sourceEpoch": 8878, "targetEpoch": 9000}))
+	require.NoError(t, err)
+	_, err = signer.SignBeaconProposal(proposal(nil))
+	require.NoError(t, err)
+
+	t.Run("attestations", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			changes map[string]interface{}
+			code    errorex.ErrorCode
+		}{
+			{
+				name:    "double vote",
+				changes: map[string]interface{}{"beaconBlockRoot": "7b5679277ca45ea74e1deebc9d3e8c0e7d6c570b3cfaf6884be144a81dac9a0f"},
+				code:    errorex.CodeSlashableDoubleVote,
+			},
+			{
+				name:    "surrounding vote",
+				changes: map[string]interface{}{"slot": 284117, "sourceEpoch": 8876, "targetEpoch": 8879},
+				code:    errorex.CodeSlashableSurround,
+			},
+			{
+				name:    "surrounded vote",
+				changes: map[string]interface{}{"slot": 284117, "sourceEpoch": 8900, "targetEpoch": 8901},
+				code:    errorex.CodeSlashableSurround,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				attestationRequest := attestation(test.changes)
+
+				err := slashableAttestation(protector, account, attestationRequest)
+				require.Error(t, err)
+				require.Equal(t, test.code, errorex.CodeOf(err))
+
+				// the signer refuses the attestation with the same message
+				_, signErr := signer.SignBeaconAttestation(attestationRequest)
+				require.EqualError(t, signErr, err.Error())
+			})
+		}
+
+		require.NoError(t, slashableAttestation(protector, account, attestation(nil)))
+	})
+
+	t.Run("proposals", func(t *testing.T) {
+		proposalRequest := proposal(map[string]interface{}{"bodyRoot": "7b5679277ca45ea74e1deebc9d3e8c0e7d6c570b3cfaf6884be144a81dac9a0f"})
+
+		err := slashableProposal(protector, account, proposalRequest)
+		require.Error(t, err)
+		require.Equal(t, errorex.CodeDoubleProposal, errorex.CodeOf(err))
+
+		// the signer refuses the proposal with the same message
+		_, signErr := signer.SignBeaconProposal(proposalRequest)
+		require.EqualError(t, signErr, err.Error())
+
+		require.NoError(t, slashableProposal(protector, account, proposal(map[string]interface{}{"slot": 284116})))
+	})
 }
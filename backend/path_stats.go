@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// Endpoints patterns
+const (
+	// StatsPattern is the path pattern for stats endpoint
+	StatsPattern = "stats"
+
+	// AccountStatsPattern is the path pattern for account stats endpoint
+	AccountStatsPattern = "accounts/" + publicKeyRegex + "/stats"
+)
+
+func statsPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         StatsPattern,
+			HelpSynopsis:    "Manage the signing stats of all accounts",
+			HelpDescription: `Read the aggregated signing stats of the accounts or reset them`,
+			Fields:          map[string]*framework.FieldSchema{},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathReadStats,
+				logical.DeleteOperation: b.pathResetStats,
+			},
+		},
+		&framework.Path{
+			Pattern:         AccountStatsPattern,
+			HelpSynopsis:    "Manage the signing stats of the account",
+			HelpDescription: `Read the signing stats of the account or reset them`,
+			Fields: map[string]*framework.FieldSchema{
+				"public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Public key of the account",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathReadAccountStats,
+				logical.DeleteOperation: b.pathResetAccountStats,
+			},
+		},
+	}
+}
+
+func (b *backend) pathReadStats(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accountStats, err := b.stats.list(store.NewHashicorpVaultStore(ctx, req.Storage, ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list account stats")
+	}
+
+	total := store.NewAccountStats()
+	for _, stats := range accountStats {
+		total.Merge(stats)
+	}
+
+	responseData := statsResponseData(total)
+	responseData["accounts"] = len(accountStats)
+	return &logical.Response{
+		Data: responseData,
+	}, nil
+}
+
+func (b *backend) pathResetStats(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	reset, err := b.stats.resetAll(store.NewHashicorpVaultStore(ctx, req.Storage, ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reset account stats")
+	}
+	b.requestLogger(req).Info("Reset account stats", "accounts", reset, "accessor", req.ClientTokenAccessor)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"accounts": reset,
+		},
+	}, nil
+}
+
+func (b *backend) pathReadAccountStats(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	storage, publicKey, res, err := b.openAccountMetadataStore(ctx, req, data)
+	if res != nil || err != nil {
+		return res, err
+	}
+
+	stats, err := b.stats.open(storage, publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open account stats")
+	}
+
+	return &logical.Response{
+		Data: statsResponseData(stats),
+	}, nil
+}
+
+func (b *backend) pathResetAccountStats(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey := strings.ToLower(data.Get("public_key").(string))
+	if err := b.stats.reset(store.NewHashicorpVaultStore(ctx, req.Storage, ""), publicKey); err != nil {
+		return nil, errors.Wrap(err, "failed to reset account stats")
+	}
+	b.requestLogger(req).Info("Reset account stats", "public_key", publicKey, "accessor", req.ClientTokenAccessor)
+
+	return nil, nil
+}
+
+// flushAccountStats stores the stats of the signing decisions counted since the last flush.
+func (b *backend) flushAccountStats(ctx context.Context, req *logical.Request) error {
+	return errors.Wrap(b.stats.flush(store.NewHashicorpVaultStore(ctx, req.Storage, "")), "failed to flush account stats")
+}
+
+func statsResponseData(stats *store.AccountStats) map[string]interface{} {
+	return map[string]interface{}{
+		"signed":                        stats.Signed,
+		"refused":                       stats.Refused,
+		"last_attestation_slot":         stats.LastAttestationSlot,
+		"last_attestation_source_epoch": stats.LastAttestationSourceEpoch,
+		"last_attestation_target_epoch": stats.LastAttestationTargetEpoch,
+		"last_attestation_time":         statsTime(stats.LastAttestationTime),
+		"last_proposal_slot":            stats.LastProposalSlot,
+		"last_proposal_time":            statsTime(stats.LastProposalTime),
+		"last_refusal_time":             statsTime(stats.LastRefusalTime),
+	}
+}
+
+// statsTime formats the given time in nanoseconds, empty if it never happened.
+func statsTime(nanoseconds int64) string {
+	if nanoseconds == 0 {
+		return ""
+	}
+
+	return time.Unix(0, nanoseconds).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestStats(t *testing.T) {
+	b, _ := getBackend(t)
+
+	req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	publicKey := basicAttestationData()["public_key"].(string)
+	sign := func(path string, data map[string]interface{}) {
+		signReq := logical.TestRequest(t, logical.CreateOperation, path)
+		signReq.Storage = req.Storage
+		signReq.Data = data
+		b.HandleRequest(context.Background(), signReq)
+	}
+	request := func(operation logical.Operation, path string) *logical.Response {
+		statsReq := logical.TestRequest(t, operation, path)
+		statsReq.Storage = req.Storage
+		res, err := b.HandleRequest(context.Background(), statsReq)
+		require.NoError(t, err)
+		return res
+	}
+
+	sign("accounts/sign-attestation", basicAttestationData())
+	slashable := basicAttestationData()
+	slashable["beaconBlockRoot"] = "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0"
+	sign("accounts/sign-attestation", slashable)
+	sign("accounts/sign-proposal", basicProposalData())
+	unknown := basicProposalData()
+	unknown["public_key"] = "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3270"
+	sign("accounts/sign-proposal", unknown)
+
+	t.Run("Read account stats", func(t *testing.T) {
+		res := request(logical.ReadOperation, "accounts/"+publicKey+"/stats")
+		require.Equal(t, map[string]uint64{"attestation": 1, "proposal": 1}, res.Data["signed"])
+		require.Equal(t, map[string]uint64{"slashable": 1}, res.Data["refused"])
+		require.EqualValues(t, 284115, res.Data["last_attestation_slot"])
+		require.EqualValues(t, 8877, res.Data["last_attestation_source_epoch"])
+		require.EqualValues(t, 8878, res.Data["last_attestation_target_epoch"])
+		require.EqualValues(t, 284115, res.Data["last_proposal_slot"])
+		require.NotEmpty(t, res.Data["last_attestation_time"])
+		require.NotEmpty(t, res.Data["last_refusal_time"])
+	})
+
+	t.Run("Unknown accounts have no stats", func(t *testing.T) {
+		res := request(logical.ReadOperation, "accounts/"+unknown["public_key"].(string)+"/stats")
+		require.EqualValues(t, 404, res.Data["http_status_code"])
+	})
+
+	t.Run("Read aggregated stats", func(t *testing.T) {
+		res := request(logical.ReadOperation, "stats")
+		require.Equal(t, 1, res.Data["accounts"])
+		require.Equal(t, map[string]uint64{"attestation": 1, "proposal": 1}, res.Data["signed"])
+		require.Equal(t, map[string]uint64{"slashable": 1}, res.Data["refused"])
+	})
+
+	t.Run("Reset account stats", func(t *testing.T) {
+		request(logical.DeleteOperation, "accounts/"+publicKey+"/stats")
+		res := request(logical.ReadOperation, "accounts/"+publicKey+"/stats")
+		require.Empty(t, res.Data["signed"])
+		require.Equal(t, "", res.Data["last_attestation_time"])
+	})
+
+	t.Run("Reset all stats", func(t *testing.T) {
+		sign("accounts/sign-attestation", slashable)
+		res := request(logical.DeleteOperation, "stats")
+		require.Equal(t, 1, res.Data["accounts"])
+
+		res = request(logical.ReadOperation, "stats")
+		require.Equal(t, 0, res.Data["accounts"])
+	})
+
+	t.Run("Concurrent decisions of an account are all counted", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				b.(*backend).stats.add(&store.AuditRecord{
+					Time:        int64(i + 1),
+					PublicKey:   publicKey,
+					MessageType: store.AuditMessageAttestation,
+					Outcome:     store.AuditOutcomeSigned,
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		res := request(logical.ReadOperation, "accounts/"+publicKey+"/stats")
+		require.Equal(t, map[string]uint64{"attestation": 20}, res.Data["signed"])
+	})
+
+	t.Run("Stats are stored by the periodic function", func(t *testing.T) {
+		vaultStore := store.NewHashicorpVaultStore(context.Background(), req.Storage, "")
+		stored, err := vaultStore.OpenAccountStats(publicKey)
+		require.NoError(t, err)
+		require.Empty(t, stored.Signed)
+
+		require.NoError(t, b.(*backend).periodic(context.Background(), req))
+
+		stored, err = vaultStore.OpenAccountStats(publicKey)
+		require.NoError(t, err)
+		require.Equal(t, map[string]uint64{"attestation": 20}, stored.Signed)
+
+		res := request(logical.ReadOperation, "accounts/"+publicKey+"/stats")
+		require.Equal(t, map[string]uint64{"attestation": 20}, res.Data["signed"])
+	})
+}
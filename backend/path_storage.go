@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 
+	"github.com/bloxapp/eth2-key-manager/core"
 	"github.com/bloxapp/eth2-key-manager/stores/in_memory"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/pkg/errors"
 
 	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // Endpoints patterns
@@ -30,6 +33,11 @@ func storagePaths(b *backend) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "storage to update",
 				},
+				"dry_run": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "Return the changes the update would make without writing anything",
+					Default:     false,
+				},
 			},
 			ExistenceCheck: b.pathExistenceCheck,
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -43,19 +51,26 @@ func (b *backend) pathStorageUpdate(ctx context.Context, req *logical.Request, d
 	storage := data.Get("data").(string)
 	storageBytes, err := hex.DecodeString(storage)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to HEX decode storage")
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("failed to HEX decode storage"))
 	}
 
-	var inMemStore *in_memory.InMemStore
-	err = json.Unmarshal(storageBytes, &inMemStore)
+	inMemStore, err := decodeInMemStore(storageBytes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to JSON un-marshal storage")
+		return b.prepareErrorResponse(err)
+	}
+
+	if data.Get("dry_run").(bool) {
+		return b.storageUpdateDiff(ctx, req, inMemStore)
 	}
 
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+
 	_, err = store.FromInMemoryStore(ctx, inMemStore, req.Storage)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to update storage")
 	}
+	b.requestLogger(req).Info("Updated storage", "operation", "storage_update", "network", inMemStore.Network())
 
 	return &logical.Response{
 		Data: map[string]interface{}{
@@ -63,3 +78,69 @@ func (b *backend) pathStorageUpdate(ctx context.Context, req *logical.Request, d
 		},
 	}, nil
 }
+
+// storageUpdateDiff returns the changes of replacing the wallet with the given in-memory store.
+func (b *backend) storageUpdateDiff(ctx context.Context, req *logical.Request, inMemStore *in_memory.InMemStore) (*logical.Response, error) {
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, inMemStore.Network())
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	diff, err := storage.DiffInMemoryStore(inMemStore)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to diff storage")
+	}
+
+	responseData := map[string]interface{}{
+		"dry_run":               true,
+		"accounts_added":        diff.Added,
+		"accounts_removed":      diff.Removed,
+		"accounts_unchanged":    diff.Unchanged,
+		"slashing_history_lost": diff.LostSlashingHistory,
+		"network":               inMemStore.Network(),
+		"network_mismatch":      false,
+	}
+
+	// The mount may not be configured yet
+	if config, err := b.readConfig(ctx, req.Storage); err == nil {
+		responseData["mount_network"] = config.Network
+		responseData["network_mismatch"] = config.Network != inMemStore.Network()
+	}
+
+	return &logical.Response{
+		Data: responseData,
+	}, nil
+}
+
+// decodeInMemStore decodes the JSON encoded in-memory store of a storage update.
+// The key manager library panics on networks it doesn't know, so those are refused before decoding.
+// Invalid storage is returned as a bad request error.
+func decodeInMemStore(data []byte) (ret *in_memory.InMemStore, err error) {
+	var header struct {
+		Network string `json:"network"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, errorex.NewErrBadRequest("failed to JSON un-marshal storage")
+	}
+
+	network, err := hex.DecodeString(header.Network)
+	if err != nil {
+		return nil, errorex.NewErrBadRequest("failed to HEX decode storage network")
+	}
+	if !keyManagerNetwork(core.Network(network)) {
+		return nil, errorex.NewErrBadRequest(fmt.Sprintf("storage of network '%s' is not supported", network))
+	}
+
+	// The library asserts the types of the fields it decodes
+	defer func() {
+		if r := recover(); r != nil {
+			ret, err = nil, errorex.NewErrBadRequest("failed to JSON un-marshal storage")
+		}
+	}()
+
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, errorex.NewErrBadRequest("failed to JSON un-marshal storage")
+	}
+	return ret, nil
+}
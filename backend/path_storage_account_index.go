@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// Endpoints patterns
+const (
+	// StorageAccountIndexPattern is the path pattern for account index endpoint
+	StorageAccountIndexPattern = "storage/account-index"
+)
+
+func storageAccountIndexPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         StorageAccountIndexPattern,
+			HelpSynopsis:    "Manage the account index",
+			HelpDescription: `Check the public key to account index against the stored accounts and rebuild it`,
+			ExistenceCheck:  b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathStorageAccountIndexRebuild,
+				logical.ReadOperation:   b.pathStorageAccountIndexCheck,
+			},
+		},
+	}
+}
+
+func (b *backend) pathStorageAccountIndexCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	report, err := storage.CheckAccountIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check account index")
+	}
+
+	if !report.Consistent() {
+		b.requestLogger(req).Warn("Account index is inconsistent", "operation", "account_index_check", "missing", len(report.Missing), "dangling", len(report.Dangling), "mismatched", len(report.Mismatched))
+		b.sendEvent(ctx, req, &WebhookEvent{
+			Type:   WebhookEventIntegrityFailure,
+			Reason: fmt.Sprintf("account index is inconsistent: %d missing, %d dangling, %d mismatched", len(report.Missing), len(report.Dangling), len(report.Mismatched)),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"consistent": report.Consistent(),
+			"accounts":   report.Accounts,
+			"missing":    report.Missing,
+			"dangling":   report.Dangling,
+			"mismatched": report.Mismatched,
+		},
+	}, nil
+}
+
+func (b *backend) pathStorageAccountIndexRebuild(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, "")
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	indexed, err := storage.RebuildAccountIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to rebuild account index")
+	}
+
+	// Drop the cached index entries
+	if err := store.RotateGeneration(ctx, req.Storage); err != nil {
+		return nil, errors.Wrap(err, "failed to rotate storage generation")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"indexed": indexed,
+		},
+	}, nil
+}
@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestStorageAccountIndex(t *testing.T) {
+	b, _ := getBackend(t)
+
+	t.Run("Successfully Check And Rebuild Account Index", func(t *testing.T) {
+		ctx := context.Background()
+		req := logical.TestRequest(t, logical.ReadOperation, "storage/account-index")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, true, res.Data["consistent"])
+		require.Equal(t, 1, res.Data["accounts"])
+
+		// drop the index entry of the account
+		publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+		require.NoError(t, req.Storage.Delete(ctx, fmt.Sprintf(store.AccountIndexPath, publicKey)))
+
+		res, err = b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, false, res.Data["consistent"])
+		require.Equal(t, []string{publicKey}, res.Data["missing"])
+
+		rebuildReq := logical.TestRequest(t, logical.CreateOperation, "storage/account-index")
+		rebuildReq.Storage = req.Storage
+		res, err = b.HandleRequest(ctx, rebuildReq)
+		require.NoError(t, err)
+		require.Equal(t, 1, res.Data["indexed"])
+
+		res, err = b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, true, res.Data["consistent"])
+	})
+}
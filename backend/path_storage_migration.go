@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// Endpoints patterns
+const (
+	// StorageMigrationPattern is the path pattern for storage migration endpoint
+	StorageMigrationPattern = "storage/migration"
+)
+
+func storageMigrationPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         StorageMigrationPattern,
+			HelpSynopsis:    "Manage storage schema",
+			HelpDescription: `Show the storage schema version and run pending migrations`,
+			ExistenceCheck:  b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathStorageMigrate,
+				logical.ReadOperation:   b.pathStorageMigrationRead,
+			},
+		},
+	}
+}
+
+func (b *backend) pathStorageMigrationRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	schemaVersion, err := store.NewHashicorpVaultStore(ctx, req.Storage, "").SchemaVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get schema version")
+	}
+
+	var pending []map[string]interface{}
+	for _, migration := range store.Migrations {
+		if migration.Version > schemaVersion {
+			pending = append(pending, migrationResponseData(migration))
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"schema_version":         schemaVersion,
+			"current_schema_version": store.CurrentSchemaVersion,
+			"pending":                pending,
+		},
+	}, nil
+}
+
+func (b *backend) pathStorageMigrate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	migrations, err := b.migrate(ctx, req.Storage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to migrate storage")
+	}
+
+	applied := make([]map[string]interface{}, len(migrations))
+	for i, migration := range migrations {
+		applied[i] = migrationResponseData(migration)
+	}
+	b.requestLogger(req).Info("Migrated storage", "operation", "storage_migrate", "applied", len(migrations), "schema_version", store.CurrentSchemaVersion)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"schema_version": store.CurrentSchemaVersion,
+			"applied":        applied,
+		},
+	}, nil
+}
+
+func migrationResponseData(migration store.Migration) map[string]interface{} {
+	return map[string]interface{}{
+		"version":     migration.Version,
+		"description": migration.Description,
+	}
+}
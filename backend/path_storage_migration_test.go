@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestStorageMigration(t *testing.T) {
+	b, _ := getBackend(t)
+
+	t.Run("Successfully Migrate Storage", func(t *testing.T) {
+		ctx := context.Background()
+		req := logical.TestRequest(t, logical.ReadOperation, "storage/migration")
+		setupBaseStorage(t, req)
+
+		res, err := b.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, 0, res.Data["schema_version"])
+		require.EqualValues(t, store.CurrentSchemaVersion, res.Data["current_schema_version"])
+		require.Len(t, res.Data["pending"], len(store.Migrations))
+
+		migrateReq := logical.TestRequest(t, logical.CreateOperation, "storage/migration")
+		migrateReq.Storage = req.Storage
+		res, err = b.HandleRequest(ctx, migrateReq)
+		require.NoError(t, err)
+		require.EqualValues(t, store.CurrentSchemaVersion, res.Data["schema_version"])
+		require.Len(t, res.Data["applied"], len(store.Migrations))
+
+		versionReq := logical.TestRequest(t, logical.ReadOperation, "version")
+		versionReq.Storage = req.Storage
+		res, err = b.HandleRequest(ctx, versionReq)
+		require.NoError(t, err)
+		require.Equal(t, "test", res.Data["version"])
+		require.EqualValues(t, store.CurrentSchemaVersion, res.Data["schema_version"])
+	})
+}
@@ -52,23 +52,25 @@ func (b *backend) pathSlashingStorageBatchUpdate(ctx context.Context, req *logic
 
 	// bring up KeyVault and wallet
 	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
 	options := vault.KeyVaultOptions{}
 	options.SetStorage(storage)
 
-	// Open wallet
-	kv, err := vault.OpenKeyVault(&options)
-	if err != nil {
+	// Open wallet, accounts are looked up by the account index
+	if _, err := vault.OpenKeyVault(&options); err != nil {
 		return nil, errors.Wrap(err, "failed to open key vault")
 	}
 
-	wallet, err := kv.Wallet()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve wallet")
-	}
+	// Backups and restores wait for the history to be stored
+	b.signLock.RLock()
+	defer b.signLock.RUnlock()
 
 	// Load accounts slashing history
 	for publicKey, data := range req.Data {
-		account, err := wallet.AccountByPublicKey(publicKey)
+		account, err := storage.AccountByPublicKey(publicKey)
 		if err != nil {
 			if err == wallet_hd.ErrAccountNotFound {
 				return b.notFoundResponse()
@@ -82,6 +84,7 @@ func (b *backend) pathSlashingStorageBatchUpdate(ctx context.Context, req *logic
 			return b.prepareErrorResponse(err)
 		}
 	}
+	b.requestLogger(req).Info("Updated slashing history", "operation", "slashing_storage_update", "accounts", len(req.Data))
 
 	return &logical.Response{
 		Data: map[string]interface{}{
@@ -99,6 +102,10 @@ func (b *backend) pathSlashingStorageBatchRead(ctx context.Context, req *logical
 
 	// bring up KeyVault and wallet
 	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	storage.SetLogger(b.requestLogger(req))
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
 	options := vault.KeyVaultOptions{}
 	options.SetStorage(storage)
 
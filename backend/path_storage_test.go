@@ -12,8 +12,10 @@ import (
 	uuid "github.com/google/uuid"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 
 	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 func _byteArray(input string) []byte {
@@ -87,6 +89,7 @@ func TestStorage(t *testing.T) {
 		require.NoError(t, err)
 
 		vault := store.NewHashicorpVaultStore(context.Background(), logicalStorage, core.MainNetwork)
+		require.NoError(t, vault.EnableEncryption())
 		wallet2, err := vault.OpenWallet()
 		require.NoError(t, err)
 		require.Equal(t, wallet.ID().String(), wallet2.ID().String())
@@ -95,4 +98,99 @@ func TestStorage(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, acc.ID().String(), acc2.ID().String())
 	})
+
+	t.Run("reject invalid storage", func(t *testing.T) {
+		for _, test := range []struct {
+			data    string
+			message string
+		}{
+			{data: "zz", message: "failed to HEX decode storage"},
+			{data: hex.EncodeToString([]byte("{")), message: "failed to JSON un-marshal storage"},
+			{data: hex.EncodeToString([]byte(`{"network":"zz"}`)), message: "failed to HEX decode storage network"},
+			{data: hex.EncodeToString([]byte(`{"network":"` + hex.EncodeToString([]byte("main")) + `","wallet":1}`)), message: "failed to JSON un-marshal storage"},
+		} {
+			req := logical.TestRequest(t, logical.CreateOperation, "storage")
+			req.Data = map[string]interface{}{
+				"data": test.data,
+			}
+			res, err := b.HandleRequest(context.Background(), req)
+			require.NoError(t, err)
+			requireErrorCode(t, res, errorex.CodeBadRequest, test.message)
+		}
+	})
+}
+
+func TestStorageDryRun(t *testing.T) {
+	b, _ := getBackend(t)
+	inMemStore, _, err := baseInmemStorage()
+	require.NoError(t, err)
+
+	byts, err := json.Marshal(inMemStore)
+	require.NoError(t, err)
+	data := hex.EncodeToString(byts)
+	publicKey := "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+
+	t.Run("dry run on empty mount", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "storage")
+		req.Data = map[string]interface{}{
+			"data":    data,
+			"dry_run": true,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, res.Data["dry_run"].(bool))
+		require.Equal(t, []string{publicKey}, res.Data["accounts_added"])
+		require.Empty(t, res.Data["accounts_removed"])
+		require.False(t, res.Data["network_mismatch"].(bool))
+
+		// nothing was written
+		_, err = store.NewHashicorpVaultStore(context.Background(), req.Storage, core.MainNetwork).OpenWallet()
+		require.Equal(t, store.ErrWalletNotFound, err)
+	})
+
+	t.Run("dry run with different network", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "storage")
+		entry, err := logical.StorageEntryJSON("config", Config{
+			Network: core.TestNetwork,
+		})
+		require.NoError(t, err)
+		require.NoError(t, req.Storage.Put(context.Background(), entry))
+		req.Data = map[string]interface{}{
+			"data":    data,
+			"dry_run": true,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, res.Data["network_mismatch"].(bool))
+	})
+
+	t.Run("dry run removing account with slashing history", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "storage")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		pubKey, err := e2types.BLSPublicKeyFromBytes(_byteArray(publicKey))
+		require.NoError(t, err)
+		vault := store.NewHashicorpVaultStore(context.Background(), req.Storage, core.MainNetwork)
+		require.NoError(t, vault.SaveProposal(pubKey, &core.BeaconBlockHeader{
+			Slot:          1,
+			ProposerIndex: 1,
+			BodyRoot:      []byte{1, 2, 3},
+			ParentRoot:    []byte{1, 2, 3},
+			StateRoot:     []byte{1, 2, 3},
+		}))
+
+		emptyStore := in_memory.NewInMemStore(core.MainNetwork)
+		require.NoError(t, emptyStore.SaveWallet(wallet_hd.NewHDWallet(&core.WalletContext{Storage: emptyStore})))
+		byts, err := json.Marshal(emptyStore)
+		require.NoError(t, err)
+
+		req.Data = map[string]interface{}{
+			"data":    hex.EncodeToString(byts),
+			"dry_run": true,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, []string{publicKey}, res.Data["accounts_removed"])
+		require.Equal(t, []string{publicKey}, res.Data["slashing_history_lost"])
+	})
 }
@@ -5,6 +5,9 @@ import (
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
 )
 
 // Endpoints patterns
@@ -29,9 +32,15 @@ func versionPaths(b *backend) []*framework.Path {
 }
 
 func (b *backend) pathVersion(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	schemaVersion, err := store.NewHashicorpVaultStore(ctx, req.Storage, "").SchemaVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get schema version")
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"version": b.Version,
+			"version":        b.Version,
+			"schema_version": schemaVersion,
 		},
 	}, nil
 }
@@ -0,0 +1,344 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	vault "github.com/bloxapp/eth2-key-manager"
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/backend/wallet_nd"
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// WalletPattern is the path pattern for wallet endpoint
+	WalletPattern = "wallet"
+
+	// WalletAccountsPattern is the path pattern for wallet accounts endpoint
+	WalletAccountsPattern = "wallet/accounts"
+
+	// WalletAccountsImportPattern is the path pattern for wallet accounts import endpoint
+	WalletAccountsImportPattern = "wallet/accounts/import"
+)
+
+// keystore is the EIP-2335 keystore of an imported key.
+type keystore struct {
+	Crypto map[string]interface{} `json:"crypto"`
+	PubKey string                 `json:"pubkey"`
+}
+
+func walletPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         WalletPattern,
+			HelpSynopsis:    "Create HD wallet",
+			HelpDescription: `Generate a new seed and create an HD wallet inside Vault`,
+			Fields: map[string]*framework.FieldSchema{
+				"return_mnemonic": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "True if the mnemonic should be returned for offline backup. It is never returned again.",
+					Default:     false,
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathWalletCreate,
+			},
+		},
+		&framework.Path{
+			Pattern:         WalletAccountsPattern,
+			HelpSynopsis:    "Create wallet account",
+			HelpDescription: `Derive a new validator account at the next EIP-2334 index`,
+			Fields:          map[string]*framework.FieldSchema{},
+			ExistenceCheck:  b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathWalletAccountCreate,
+			},
+		},
+		&framework.Path{
+			Pattern:         WalletAccountsImportPattern,
+			HelpSynopsis:    "Import wallet account",
+			HelpDescription: `Import a standalone validator key from an EIP-2335 keystore`,
+			Fields: map[string]*framework.FieldSchema{
+				"keystore": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "EIP-2335 keystore JSON of the validation key",
+				},
+				"password": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Password of the keystore",
+				},
+				"withdrawal_public_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "HEX encoded withdrawal public key of the account, if known",
+					Default:     "",
+				},
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the account",
+					Default:     "",
+				},
+				"convert_wallet": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "True to convert an existing HD wallet to an ND wallet, the import is refused otherwise",
+					Default:     false,
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathWalletAccountImport,
+			},
+		},
+	}
+}
+
+func (b *backend) pathWalletCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	// The wallet must not be created twice
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	// Refuse to override an existing wallet
+	if _, err := storage.OpenWallet(); err == nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("wallet already exists"))
+	} else if err != store.ErrWalletNotFound {
+		return nil, errors.Wrap(err, "failed to open wallet")
+	}
+
+	// Generate seed
+	entropy, err := core.GenerateNewEntropy()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate entropy")
+	}
+
+	seed, err := core.SeedFromEntropy(entropy, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate seed")
+	}
+
+	// The seed is saved first, an HD wallet without its seed can't derive accounts
+	if err := storage.SaveWalletSeed(seed); err != nil {
+		return nil, errors.Wrap(err, "failed to save wallet seed")
+	}
+
+	// Create wallet
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+	options.SetSeed(seed)
+
+	kv, err := vault.NewKeyVault(&options)
+	if err != nil {
+		b.deleteWalletSeed(req, storage)
+		return nil, errors.Wrap(err, "failed to create key vault")
+	}
+
+	wallet, err := kv.Wallet()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve wallet")
+	}
+
+	responseData := map[string]interface{}{
+		"id": wallet.ID().String(),
+	}
+
+	if data.Get("return_mnemonic").(bool) {
+		mnemonic, err := core.EntropyToMnemonic(entropy)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create mnemonic")
+		}
+		responseData["mnemonic"] = mnemonic
+	}
+
+	return &logical.Response{
+		Data: responseData,
+	}, nil
+}
+
+// deleteWalletSeed rolls back the seed of a wallet that failed to be created.
+func (b *backend) deleteWalletSeed(req *logical.Request, storage *store.HashicorpVaultStore) {
+	if err := storage.DeleteWalletSeed(); err != nil {
+		b.requestLogger(req).Error("Failed to roll back wallet seed", "error", err)
+	}
+}
+
+func (b *backend) pathWalletAccountCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	// Only one account can be derived at a time, the wallet is opened inside the lock
+	// so concurrent requests don't derive the same index
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	options := vault.KeyVaultOptions{}
+	options.SetStorage(storage)
+
+	// Open wallet
+	kv, err := vault.OpenKeyVault(&options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open key vault")
+	}
+
+	wallet, err := kv.Wallet()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve wallet")
+	}
+
+	seed, err := storage.OpenWalletSeed()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open wallet seed")
+	}
+
+	if seed == nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("wallet seed is not stored in vault"))
+	}
+
+	account, err := wallet.CreateValidatorAccount(seed, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create account")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":               account.ID().String(),
+			"name":             account.Name(),
+			"validationPubKey": hex.EncodeToString(account.ValidatorPublicKey().Marshal()),
+			"withdrawalPubKey": hex.EncodeToString(account.WithdrawalPublicKey().Marshal()),
+		},
+	}, nil
+}
+
+func (b *backend) pathWalletAccountImport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Load config
+	config, err := b.configured(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	// Parse request data
+	var ks keystore
+	if err := json.Unmarshal([]byte(data.Get("keystore").(string)), &ks); err != nil || ks.Crypto == nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid keystore"))
+	}
+
+	privateKey, err := keystorev4.New().Decrypt(ks.Crypto, data.Get("password").(string))
+	if err != nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("failed to decrypt keystore"))
+	}
+
+	var withdrawalPubKey e2types.PublicKey
+	if withdrawalPublicKey := data.Get("withdrawal_public_key").(string); len(withdrawalPublicKey) > 0 {
+		withdrawalPubKeyBytes, err := hex.DecodeString(strings.TrimPrefix(withdrawalPublicKey, "0x"))
+		if err != nil {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid withdrawal public key"))
+		}
+
+		if withdrawalPubKey, err = e2types.BLSPublicKeyFromBytes(withdrawalPubKeyBytes); err != nil {
+			return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid withdrawal public key"))
+		}
+	}
+
+	// The wallet is read and written under the lock so concurrent changes aren't lost
+	b.walletLock.Lock()
+	defer b.walletLock.Unlock()
+
+	storage := store.NewHashicorpVaultStore(ctx, req.Storage, config.Network)
+	if err := storage.EnableEncryption(); err != nil {
+		return nil, errors.Wrap(err, "failed to enable storage encryption")
+	}
+
+	// Imported keys live in a non-deterministic wallet
+	vault.InitCrypto()
+	walletContext := &core.WalletContext{Storage: storage}
+	var wallet *wallet_nd.NDWallet
+	var converted bool
+	switch w, err := storage.OpenWallet(); {
+	case err == store.ErrWalletNotFound:
+		wallet = wallet_nd.NewNDWallet(walletContext)
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to open wallet")
+	default:
+		switch w := w.(type) {
+		case *wallet_nd.NDWallet:
+			wallet = w
+		case *wallet_hd.HDWallet:
+			if !data.Get("convert_wallet").(bool) {
+				return b.prepareErrorResponse(errorex.NewErrBadRequest("wallet is an HD wallet, set convert_wallet to convert it to an ND wallet"))
+			}
+			if wallet, err = wallet_nd.FromHDWallet(w, walletContext); err != nil {
+				return nil, errors.Wrap(err, "failed to convert HD wallet")
+			}
+			converted = true
+		default:
+			return nil, fmt.Errorf("unsupported wallet type %s", w.Type())
+		}
+	}
+
+	account, err := wallet_nd.NewImportedAccount(data.Get("name").(string), privateKey, withdrawalPubKey, walletContext)
+	if err != nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid validation key"))
+	}
+
+	validationPubKey := hex.EncodeToString(account.ValidatorPublicKey().Marshal())
+	if len(ks.PubKey) > 0 && !strings.EqualFold(strings.TrimPrefix(ks.PubKey, "0x"), validationPubKey) {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("keystore public key does not match the validation key"))
+	}
+
+	if _, err := wallet.AccountByPublicKey(validationPubKey); err == nil {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("account already exists"))
+	}
+
+	if err := wallet.AddValidatorAccount(account); err != nil {
+		return nil, errors.Wrap(err, "failed to import account")
+	}
+
+	responseData := map[string]interface{}{
+		"id":               account.ID().String(),
+		"name":             account.Name(),
+		"type":             store.NDAccountType,
+		"validationPubKey": validationPubKey,
+		"withdrawalPubKey": "",
+	}
+	if withdrawalPubKey != nil {
+		responseData["withdrawalPubKey"] = hex.EncodeToString(withdrawalPubKey.Marshal())
+	}
+
+	res := &logical.Response{
+		Data: responseData,
+	}
+	if converted {
+		res.Data["walletConverted"] = true
+		res.AddWarning("the HD wallet was converted to an ND wallet")
+	}
+	return res, nil
+}
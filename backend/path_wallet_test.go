@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+func TestWalletCreate(t *testing.T) {
+	b, _ := getBackend(t)
+
+	t.Run("Successfully Create Wallet and Accounts", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupBaseStorage(t, req)
+		req.Data = map[string]interface{}{
+			"return_mnemonic": true,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.NotEmpty(t, res.Data["id"])
+		mnemonic := res.Data["mnemonic"].(string)
+		require.NotEmpty(t, mnemonic)
+
+		// the mnemonic restores the keys derived inside vault
+		seed, err := core.SeedFromMnemonic(mnemonic, "")
+		require.NoError(t, err)
+		masterKey, err := core.MasterKeyFromSeed(seed, core.MainNetwork)
+		require.NoError(t, err)
+
+		for i, name := range []string{"account-0", "account-1"} {
+			accountReq := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+			accountReq.Storage = req.Storage
+			res, err := b.HandleRequest(context.Background(), accountReq)
+			require.NoError(t, err)
+			require.Equal(t, name, res.Data["name"])
+
+			key, err := masterKey.Derive(fmt.Sprintf("/%d/0/0", i))
+			require.NoError(t, err)
+			require.Equal(t, hex.EncodeToString(key.PublicKey().Marshal()), res.Data["validationPubKey"])
+		}
+
+		// accounts are listed
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		res, err = b.HandleRequest(context.Background(), listReq)
+		require.NoError(t, err)
+		require.Len(t, res.Data["accounts"], 2)
+	})
+
+	t.Run("Concurrently created accounts get distinct indexes", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupBaseStorage(t, req)
+		_, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+
+		const accounts = 5
+		var wg sync.WaitGroup
+		names := make(chan string, accounts)
+		for i := 0; i < accounts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				accountReq := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+				accountReq.Storage = req.Storage
+				res, err := b.HandleRequest(context.Background(), accountReq)
+				if err == nil && res != nil {
+					names <- res.Data["name"].(string)
+				}
+			}()
+		}
+		wg.Wait()
+		close(names)
+
+		var created []string
+		for name := range names {
+			created = append(created, name)
+		}
+		require.ElementsMatch(t, []string{"account-0", "account-1", "account-2", "account-3", "account-4"}, created)
+
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		res, err := b.HandleRequest(context.Background(), listReq)
+		require.NoError(t, err)
+		require.Len(t, res.Data["accounts"], accounts)
+	})
+
+	t.Run("Only one of concurrent wallet creations succeeds", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupBaseStorage(t, req)
+
+		const creations = 5
+		var wg sync.WaitGroup
+		ids := make(chan string, creations)
+		for i := 0; i < creations; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				walletReq := logical.TestRequest(t, logical.CreateOperation, "wallet")
+				walletReq.Storage = req.Storage
+				res, err := b.HandleRequest(context.Background(), walletReq)
+				if err == nil && res != nil && res.Data["id"] != nil {
+					ids <- res.Data["id"].(string)
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+		require.Len(t, ids, 1)
+
+		// The seed is the one of the created wallet
+		accountReq := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+		accountReq.Storage = req.Storage
+		res, err := b.HandleRequest(context.Background(), accountReq)
+		require.NoError(t, err)
+		require.Equal(t, "account-0", res.Data["name"])
+	})
+
+	t.Run("Mnemonic is not returned by default", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupBaseStorage(t, req)
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.NotContains(t, res.Data, "mnemonic")
+	})
+
+	t.Run("Reject Create Wallet when wallet exists", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject Create Account without seed", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+}
+
+func testKeystore(t *testing.T, key *e2types.BLSPrivateKey, password string) string {
+	crypto, err := keystorev4.New().Encrypt(key.Marshal(), password)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]interface{}{
+		"crypto":  crypto,
+		"pubkey":  hex.EncodeToString(key.PublicKey().Marshal()),
+		"version": 4,
+	})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWalletAccountImport(t *testing.T) {
+	b, _ := getBackend(t)
+	key, err := e2types.GenerateBLSPrivateKey()
+	require.NoError(t, err)
+	publicKey := hex.EncodeToString(key.PublicKey().Marshal())
+
+	t.Run("Successfully Import Account next to HD accounts", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts/import")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"keystore":       testKeystore(t, key, "password"),
+			"password":       "password",
+			"convert_wallet": true,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, publicKey, res.Data["validationPubKey"])
+		require.Equal(t, "ND", res.Data["type"])
+		require.Equal(t, "", res.Data["withdrawalPubKey"])
+		require.Equal(t, true, res.Data["walletConverted"])
+		require.Len(t, res.Warnings, 1)
+
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		res, err = b.HandleRequest(context.Background(), listReq)
+		require.NoError(t, err)
+		accounts := res.Data["accounts"].([]map[string]string)
+		require.Len(t, accounts, 2)
+		types := []string{accounts[0]["type"], accounts[1]["type"]}
+		require.ElementsMatch(t, []string{"HD", "ND"}, types)
+
+		// BLS deposit data needs a withdrawal key
+		depositReq := logical.TestRequest(t, logical.CreateOperation, "accounts/"+publicKey+"/deposit-data")
+		depositReq.Storage = req.Storage
+		res, err = b.HandleRequest(context.Background(), depositReq)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+
+		// the same key can't be imported twice
+		res, err = b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Reject Import Account into HD wallet without convert_wallet", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts/import")
+		setupBaseStorage(t, req)
+		require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+		req.Data = map[string]interface{}{
+			"keystore": testKeystore(t, key, "password"),
+			"password": "password",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		requireErrorCode(t, res, errorex.CodeBadRequest, "wallet is an HD wallet, set convert_wallet to convert it to an ND wallet")
+
+		// the wallet is left untouched
+		listReq := logical.TestRequest(t, logical.ListOperation, "accounts/")
+		listReq.Storage = req.Storage
+		res, err = b.HandleRequest(context.Background(), listReq)
+		require.NoError(t, err)
+		accounts := res.Data["accounts"].([]map[string]string)
+		require.Len(t, accounts, 1)
+		require.Equal(t, "HD", accounts[0]["type"])
+	})
+
+	t.Run("Successfully Import Account to empty mount", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts/import")
+		setupBaseStorage(t, req)
+		req.Data = map[string]interface{}{
+			"keystore":              testKeystore(t, key, "password"),
+			"password":              "password",
+			"name":                  "standalone",
+			"withdrawal_public_key": publicKey,
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "standalone", res.Data["name"])
+		require.Equal(t, publicKey, res.Data["withdrawalPubKey"])
+		require.Nil(t, res.Data["walletConverted"])
+	})
+
+	t.Run("Reject wrong password", func(t *testing.T) {
+		req := logical.TestRequest(t, logical.CreateOperation, "wallet/accounts/import")
+		setupBaseStorage(t, req)
+		req.Data = map[string]interface{}{
+			"keystore": testKeystore(t, key, "password"),
+			"password": "wrong",
+		}
+		res, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+}
@@ -0,0 +1,186 @@
+package backend
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Endpoints patterns
+const (
+	// WebhooksPattern is the path pattern for list webhooks endpoint
+	WebhooksPattern = "webhooks/?"
+
+	// WebhookPattern is the path pattern for webhook endpoint
+	WebhookPattern = "webhooks/" + webhookNameRegex
+)
+
+// webhookNameRegex matches the name of a webhook target.
+const webhookNameRegex = "(?P<name>[a-zA-Z0-9][a-zA-Z0-9_.-]*)"
+
+// webhooksBase is the storage prefix of the webhooks, keyed by name.
+const webhooksBase = "webhooks/"
+
+// Webhook is a target the events of the mount are sent to.
+type Webhook struct {
+	URL string `json:"url"`
+
+	// Secret is the HMAC-SHA256 key of the event signature, events are not signed if empty
+	Secret string `json:"secret,omitempty"`
+}
+
+func webhooksPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern:         WebhooksPattern,
+			HelpSynopsis:    "List webhooks",
+			HelpDescription: `List the names of the webhook targets of the mount`,
+			Fields:          map[string]*framework.FieldSchema{},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathWebhooksList,
+			},
+		},
+		&framework.Path{
+			Pattern:         WebhookPattern,
+			HelpSynopsis:    "Manage a webhook",
+			HelpDescription: `Manage a target of the slashing protection, account pause and integrity events`,
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the webhook",
+				},
+				"url": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "HTTP(S) URL the events are posted to",
+				},
+				"secret": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Key of the HMAC-SHA256 signature of the events, events are not signed if empty",
+					Default:     "",
+				},
+			},
+			ExistenceCheck: b.pathExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathWebhookWrite,
+				logical.UpdateOperation: b.pathWebhookWrite,
+				logical.ReadOperation:   b.pathWebhookRead,
+				logical.DeleteOperation: b.pathWebhookDelete,
+			},
+		},
+	}
+}
+
+func (b *backend) pathWebhooksList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, webhooksBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhooks")
+	}
+
+	return logical.ListResponse(names), nil
+}
+
+func (b *backend) pathWebhookWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	webhook := &Webhook{
+		URL:    data.Get("url").(string),
+		Secret: data.Get("secret").(string),
+	}
+
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || len(target.Host) == 0 {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("invalid webhook url"))
+	}
+
+	name := data.Get("name").(string)
+	entry, err := logical.StorageEntryJSON(webhooksBase+name, webhook)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal webhook")
+	}
+
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, errors.Wrap(err, "failed to save webhook")
+	}
+	b.requestLogger(req).Info("Saved webhook", "name", name, "url", webhook.URL, "accessor", req.ClientTokenAccessor)
+
+	return &logical.Response{
+		Data: webhookResponseData(webhook),
+	}, nil
+}
+
+func (b *backend) pathWebhookRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	webhook, err := readWebhook(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: webhookResponseData(webhook),
+	}, nil
+}
+
+func (b *backend) pathWebhookDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if err := req.Storage.Delete(ctx, webhooksBase+name); err != nil {
+		return nil, errors.Wrap(err, "failed to delete webhook")
+	}
+	b.requestLogger(req).Info("Deleted webhook", "name", name, "accessor", req.ClientTokenAccessor)
+
+	return nil, nil
+}
+
+// readWebhook returns the webhook with the given name, nil if it doesn't exist.
+func readWebhook(ctx context.Context, s logical.Storage, name string) (*Webhook, error) {
+	entry, err := s.Get(ctx, webhooksBase+name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get webhook %s", name)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var webhook Webhook
+	if err := entry.DecodeJSON(&webhook); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode webhook %s", name)
+	}
+
+	return &webhook, nil
+}
+
+// listWebhooks returns the webhooks of the mount.
+func listWebhooks(ctx context.Context, s logical.Storage) ([]*Webhook, error) {
+	names, err := s.List(ctx, webhooksBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhooks")
+	}
+
+	ret := make([]*Webhook, 0, len(names))
+	for _, name := range names {
+		webhook, err := readWebhook(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if webhook != nil {
+			ret = append(ret, webhook)
+		}
+	}
+
+	return ret, nil
+}
+
+// webhookResponseData returns the webhook without its secret.
+func webhookResponseData(webhook *Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"url":        webhook.URL,
+		"has_secret": len(webhook.Secret) > 0,
+	}
+}
@@ -3,12 +3,20 @@ package backend
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
+// ErrLocked is the error when the account is already being signed with.
+var ErrLocked = errorex.NewCodedError(errorex.CodeLocked, "locked")
+
 // DBLock implements DB slocking mechanism.
+// Checking and taking the lock are atomic on the node, storage writes only run on the active node.
 type DBLock struct {
 	id      uuid.UUID
 	storage logical.Storage
@@ -24,13 +32,18 @@ func NewDBLock(id uuid.UUID, storage logical.Storage) *DBLock {
 
 // Lock locks the DB.
 func (lock *DBLock) Lock() error {
+	// No other request may take the lock between the check and the put
+	stripe := dbLockStripes.get(lock.id.String())
+	stripe.Lock()
+	defer stripe.Unlock()
+
 	// if locked return error
 	locked, err := lock.IsLocked()
 	if err != nil {
 		return err
 	}
 	if locked {
-		return fmt.Errorf("locked")
+		return ErrLocked
 	}
 
 	// add lock to db
@@ -67,6 +80,48 @@ func (lock *DBLock) IsLocked() (bool, error) {
 	return entry != nil, err
 }
 
+// dbLockStripes serializes the check and the put of the DB locks.
+var dbLockStripes = newStripedMutex()
+
+// stripedMutexSize is the number of mutexes of a stripedMutex.
+const stripedMutexSize = 64
+
+// stripedMutex holds mutexes selected by key, so different keys rarely wait for each other.
+type stripedMutex struct {
+	stripes [stripedMutexSize]sync.Mutex
+}
+
+// newStripedMutex is the constructor of stripedMutex.
+func newStripedMutex() *stripedMutex {
+	return &stripedMutex{}
+}
+
+// get returns the mutex of the given key.
+func (m *stripedMutex) get(key string) *sync.Mutex {
+	return &m.stripes[stripeOf(key)]
+}
+
+// stripeOf returns the index of the stripe of the given key.
+func stripeOf(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % stripedMutexSize)
+}
+
+// lockAll locks the mutexes of all keys, in order.
+func (m *stripedMutex) lockAll() {
+	for i := range m.stripes {
+		m.stripes[i].Lock()
+	}
+}
+
+// unlockAll unlocks the mutexes locked by lockAll.
+func (m *stripedMutex) unlockAll() {
+	for i := range m.stripes {
+		m.stripes[i].Unlock()
+	}
+}
+
 func (lock *DBLock) key() string {
 	return fmt.Sprintf("lock/%s", lock.id.String())
 }
@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"github.com/pkg/errors"
+	ssz "github.com/prysmaticlabs/go-ssz"
+)
+
+// signingData is the SSZ container of an object root and its signature domain.
+type signingData struct {
+	ObjectRoot []byte `ssz-size:"32"`
+	Domain     []byte `ssz-size:"32"`
+}
+
+// computeSigningRoot returns the root to be signed for the given SSZ object and domain.
+func computeSigningRoot(object interface{}, domain []byte) ([32]byte, error) {
+	objectRoot, err := ssz.HashTreeRoot(object)
+	if err != nil {
+		return [32]byte{}, errors.Wrap(err, "failed to determine the root hash of object")
+	}
+
+	root, err := ssz.HashTreeRoot(&signingData{
+		ObjectRoot: objectRoot[:],
+		Domain:     domain,
+	})
+	if err != nil {
+		return [32]byte{}, errors.Wrap(err, "failed to determine the root hash of signing data")
+	}
+
+	return root, nil
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
+// Slot timing parameters
+const (
+	// SlotsPerEpoch is the number of slots in an epoch.
+	SlotsPerEpoch = 32
+
+	// DefaultSecondsPerSlot is the slot duration of the public networks.
+	DefaultSecondsPerSlot = 12
+
+	// DefaultSlotWindow is the default number of slots a signed slot may be away from the current slot.
+	DefaultSlotWindow = 64
+)
+
+// currentSlot returns the wall-clock slot at the given time.
+// It returns false if the genesis time or the slot duration of the network are unknown.
+func (config *Config) currentSlot(now time.Time) (uint64, bool) {
+	if config.GenesisTime == 0 || config.SecondsPerSlot == 0 {
+		return 0, false
+	}
+
+	if now.Unix() < int64(config.GenesisTime) {
+		return 0, true
+	}
+
+	return (uint64(now.Unix()) - config.GenesisTime) / config.SecondsPerSlot, true
+}
+
+// slotWindow returns the first and the last slot that may be signed at the given time.
+// It returns false if the window is not enforced.
+func (config *Config) slotWindow(now time.Time) (uint64, uint64, bool) {
+	if config.SlotWindow == 0 {
+		return 0, 0, false
+	}
+
+	current, ok := config.currentSlot(now)
+	if !ok {
+		return 0, 0, false
+	}
+
+	first := uint64(0)
+	if current > config.SlotWindow {
+		first = current - config.SlotWindow
+	}
+
+	return first, current + config.SlotWindow, true
+}
+
+// checkSlotWindow returns an error response if the given slot is outside of the signing window.
+func (b *backend) checkSlotWindow(config *Config, slot uint64) (*logical.Response, error) {
+	first, last, ok := config.slotWindow(b.clock())
+	if !ok {
+		return nil, nil
+	}
+
+	if slot < first || slot > last {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("slot is outside of the signing window"))
+	}
+
+	return nil, nil
+}
+
+// checkEpochWindow returns an error response if the target epoch is outside of the signing window
+// or the source epoch is after it.
+func (b *backend) checkEpochWindow(config *Config, sourceEpoch, targetEpoch uint64) (*logical.Response, error) {
+	first, last, ok := config.slotWindow(b.clock())
+	if !ok {
+		return nil, nil
+	}
+
+	if targetEpoch < first/SlotsPerEpoch || targetEpoch > last/SlotsPerEpoch || sourceEpoch > last/SlotsPerEpoch {
+		return b.prepareErrorResponse(errorex.NewErrBadRequest("epoch is outside of the signing window"))
+	}
+
+	return nil, nil
+}
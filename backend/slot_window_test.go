@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotWindow(t *testing.T) {
+	lb, _ := getBackend(t)
+	b := lb.(*backend)
+
+	const genesisTime = 1606824023
+	b.clock = func() time.Time {
+		return time.Unix(genesisTime+284115*DefaultSecondsPerSlot, 0)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Data = map[string]interface{}{
+		"network":      "test",
+		"genesis_time": genesisTime,
+		"slot_window":  2,
+	}
+	res, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, res.Data["slot_window"])
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+
+	sign := func(path string, data map[string]interface{}) *logical.Response {
+		signReq := logical.TestRequest(t, logical.CreateOperation, path)
+		signReq.Storage = req.Storage
+		signReq.Data = data
+		res, err := b.HandleRequest(context.Background(), signReq)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Sign attestation of the current slot", func(t *testing.T) {
+		res := sign("accounts/sign-attestation", basicAttestationData())
+		require.NotEmpty(t, res.Data["signature"], res.Data)
+	})
+
+	t.Run("Reject attestation of a future slot", func(t *testing.T) {
+		data := basicAttestationData()
+		data["slot"] = 284118
+		res := sign("accounts/sign-attestation", data)
+		require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+	})
+
+	t.Run("Reject attestation of a future target epoch", func(t *testing.T) {
+		data := basicAttestationData()
+		data["targetEpoch"] = 9000
+		res := sign("accounts/sign-attestation", data)
+		require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+	})
+
+	t.Run("Reject proposal of a past slot", func(t *testing.T) {
+		data := basicProposalData()
+		data["slot"] = 284100
+		res := sign("accounts/sign-proposal", data)
+		require.EqualValues(t, 400, res.Data["http_status_code"], res.Data)
+	})
+
+	t.Run("Sign proposal within the window", func(t *testing.T) {
+		data := basicProposalData()
+		data["slot"] = 284116
+		res := sign("accounts/sign-proposal", data)
+		require.NotEmpty(t, res.Data["signature"], res.Data)
+	})
+}
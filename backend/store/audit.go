@@ -0,0 +1,330 @@
+package store
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// Paths
+const (
+	AuditBase       = "audit/"
+	AuditBucketBase = AuditBase + "%012d/"            // record time in hours
+	AuditPath       = AuditBucketBase + "%020d-%s-%s" // record time in nanoseconds, account public key, request ID
+)
+
+// ErrInvalidPublicKey is the error when a public key isn't a HEX encoded BLS public key
+var ErrInvalidPublicKey = errors.New("invalid public key")
+
+// Signing message types
+const (
+	AuditMessageAttestation = "attestation"
+	AuditMessageProposal    = "proposal"
+	AuditMessageAggregation = "aggregation"
+)
+
+// Signing outcomes
+const (
+	// AuditOutcomeSigned is the outcome of a produced signature
+	AuditOutcomeSigned = "signed"
+
+	// AuditOutcomeSlashable is the outcome of a message refused by slashing protection
+	AuditOutcomeSlashable = "slashable"
+
+	// AuditOutcomeLocked is the outcome of a message refused because the account was being signed with
+	AuditOutcomeLocked = "locked"
+
+	// AuditOutcomeRejected is the outcome of an invalid message or a message the account may not sign
+	AuditOutcomeRejected = "rejected"
+
+	// AuditOutcomeNotFound is the outcome of a message of an unknown account
+	AuditOutcomeNotFound = "not_found"
+
+	// AuditOutcomeFailed is the outcome of a signing that failed
+	AuditOutcomeFailed = "failed"
+)
+
+// AuditRecord is a signing decision of the plugin.
+type AuditRecord struct {
+	// Time is the time of the decision in nanoseconds since the Unix epoch
+	Time        int64  `json:"time"`
+	PublicKey   string `json:"public_key"`
+	MessageType string `json:"message_type"`
+	Slot        uint64 `json:"slot,omitempty"`
+	SourceEpoch uint64 `json:"source_epoch,omitempty"`
+	TargetEpoch uint64 `json:"target_epoch,omitempty"`
+	SigningRoot string `json:"signing_root,omitempty"`
+	Outcome     string `json:"outcome"`
+	Reason      string `json:"reason,omitempty"`
+	RequestID   string `json:"request_id"`
+	Accessor    string `json:"accessor"`
+}
+
+// AuditFilter selects audit records. Zero values don't filter.
+type AuditFilter struct {
+	From      time.Time
+	To        time.Time
+	PublicKey string
+	Limit     int
+}
+
+// NormalizePublicKey returns the lower case HEX encoding of the given BLS public key, without 0x prefix.
+func NormalizePublicKey(publicKey string) (string, error) {
+	ret := strings.ToLower(strings.TrimPrefix(publicKey, "0x"))
+	decoded, err := hex.DecodeString(ret)
+	if err != nil || len(decoded) != 48 {
+		return "", ErrInvalidPublicKey
+	}
+	return ret, nil
+}
+
+// AppendAuditRecord stores the given audit record.
+// Records of invalid public keys are kept without public key in their path.
+// The request ID keeps the paths of records at the same time apart, a random one is used if there is none.
+func (store *HashicorpVaultStore) AppendAuditRecord(record *AuditRecord) error {
+	publicKey, err := NormalizePublicKey(record.PublicKey)
+	if err == nil {
+		record.PublicKey = publicKey
+	}
+
+	entry, err := logical.StorageEntryJSON(auditPath(record, publicKey), record)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode audit record")
+	}
+
+	return store.storage.Put(store.ctx, entry)
+}
+
+// ListAuditRecords returns the audit records matching the given filter, newest first.
+// Records are bucketed by hour, only the buckets of the requested time range are listed
+// and only the matching records are read.
+func (store *HashicorpVaultStore) ListAuditRecords(filter AuditFilter) ([]*AuditRecord, error) {
+	var publicKey string
+	if len(filter.PublicKey) > 0 {
+		var err error
+		if publicKey, err = NormalizePublicKey(filter.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	buckets, err := store.auditBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*AuditRecord, 0)
+	for i := len(buckets) - 1; i >= 0; i-- {
+		bucket := buckets[i]
+		if !filter.To.IsZero() && bucket > auditBucket(filter.To.UnixNano()) {
+			continue
+		}
+		if !filter.From.IsZero() && bucket < auditBucket(filter.From.UnixNano()) {
+			break
+		}
+
+		keys, err := store.auditKeys(bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := len(keys) - 1; j >= 0; j-- {
+			if filter.Limit > 0 && len(ret) == filter.Limit {
+				return ret, nil
+			}
+
+			recordTime, recordPublicKey, ok := parseAuditKey(keys[j])
+			if !ok {
+				continue
+			}
+
+			if (!filter.From.IsZero() && recordTime < filter.From.UnixNano()) ||
+				(!filter.To.IsZero() && recordTime > filter.To.UnixNano()) ||
+				(len(publicKey) > 0 && recordPublicKey != publicKey) {
+				continue
+			}
+
+			path := fmt.Sprintf(AuditBucketBase, bucket) + keys[j]
+			entry, err := store.storage.Get(store.ctx, path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get record with path '%s'", path)
+			}
+
+			if entry == nil {
+				continue
+			}
+
+			var record AuditRecord
+			if err := entry.DecodeJSON(&record); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode record with path '%s'", path)
+			}
+			ret = append(ret, &record)
+		}
+	}
+
+	return ret, nil
+}
+
+// PruneAuditRecords deletes the audit records older than the given time.
+// Buckets are pruned oldest first, it stops at the first record at or after the given time.
+// It returns the number of deleted records.
+func (store *HashicorpVaultStore) PruneAuditRecords(before time.Time) (int, error) {
+	buckets, err := store.auditBuckets()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, bucket := range buckets {
+		if bucket > auditBucket(before.UnixNano()) {
+			break
+		}
+
+		keys, err := store.auditKeys(bucket)
+		if err != nil {
+			return pruned, err
+		}
+
+		for _, key := range keys {
+			recordTime, _, ok := parseAuditKey(key)
+			if !ok {
+				continue
+			}
+
+			// Keys are sorted by time
+			if recordTime >= before.UnixNano() {
+				return pruned, nil
+			}
+
+			path := fmt.Sprintf(AuditBucketBase, bucket) + key
+			if err := store.storage.Delete(store.ctx, path); err != nil {
+				return pruned, errors.Wrapf(err, "failed to delete record with path '%s'", path)
+			}
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
+
+// bucketAuditRecords moves the audit records of schema version 4 and older to their hour bucket.
+func (store *HashicorpVaultStore) bucketAuditRecords() error {
+	keys, err := store.storage.List(store.ctx, AuditBase)
+	if err != nil {
+		return errors.Wrap(err, "failed to list audit records")
+	}
+
+	for _, key := range keys {
+		// Buckets
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+
+		recordTime, publicKey, ok := parseAuditKey(key)
+		if !ok {
+			continue
+		}
+
+		path := AuditBase + key
+		entry, err := store.storage.Get(store.ctx, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get record with path '%s'", path)
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		var record AuditRecord
+		if err := entry.DecodeJSON(&record); err != nil {
+			return errors.Wrapf(err, "failed to decode record with path '%s'", path)
+		}
+		record.Time = recordTime
+
+		if _, err := NormalizePublicKey(publicKey); err != nil {
+			publicKey = ""
+		}
+		entry.Key = auditPath(&record, publicKey)
+		if err := store.storage.Put(store.ctx, entry); err != nil {
+			return errors.Wrapf(err, "failed to put record with path '%s'", entry.Key)
+		}
+
+		if err := store.storage.Delete(store.ctx, path); err != nil {
+			return errors.Wrapf(err, "failed to delete record with path '%s'", path)
+		}
+	}
+
+	return nil
+}
+
+// auditBuckets returns the sorted hour buckets of the audit records.
+func (store *HashicorpVaultStore) auditBuckets() ([]int64, error) {
+	keys, err := store.storage.List(store.ctx, AuditBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit buckets")
+	}
+
+	ret := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/") {
+			continue
+		}
+
+		bucket, err := strconv.ParseInt(strings.TrimSuffix(key, "/"), 10, 64)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, bucket)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return ret, nil
+}
+
+// auditKeys returns the sorted keys of the audit records of the given bucket.
+func (store *HashicorpVaultStore) auditKeys(bucket int64) ([]string, error) {
+	keys, err := store.storage.List(store.ctx, fmt.Sprintf(AuditBucketBase, bucket))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit records")
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// auditBucket returns the hour bucket of the given time in nanoseconds.
+func auditBucket(recordTime int64) int64 {
+	return recordTime / int64(time.Hour)
+}
+
+// auditPath returns the path of the given audit record with the given normalized public key.
+func auditPath(record *AuditRecord, publicKey string) string {
+	requestID := record.RequestID
+	if len(requestID) == 0 {
+		requestID = uuid.New().String()
+	}
+	return fmt.Sprintf(AuditPath, auditBucket(record.Time), record.Time, publicKey, requestID)
+}
+
+// parseAuditKey returns the time and the public key of the given audit record key.
+func parseAuditKey(key string) (int64, string, bool) {
+	parts := strings.SplitN(key, "-", 3)
+	if len(parts) < 2 {
+		return 0, "", false
+	}
+
+	recordTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return recordTime, parts[1], true
+}
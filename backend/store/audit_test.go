@@ -0,0 +1,141 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestAuditRecords(t *testing.T) {
+	hashi := store.NewHashicorpVaultStore(context.Background(), &logical.InmemStorage{}, core.TestNetwork)
+
+	keyA := strings.Repeat("aa", 48)
+	keyB := strings.Repeat("bb", 48)
+
+	// records are an hour apart, each in its own bucket
+	start := time.Unix(1600000000, 0)
+	for i, publicKey := range []string{keyA, keyB, "0x" + strings.ToUpper(keyA), keyB} {
+		require.NoError(t, hashi.AppendAuditRecord(&store.AuditRecord{
+			Time:        start.Add(time.Duration(i) * time.Hour).UnixNano(),
+			PublicKey:   publicKey,
+			MessageType: store.AuditMessageAttestation,
+			Slot:        uint64(i),
+			Outcome:     store.AuditOutcomeSigned,
+		}))
+	}
+
+	t.Run("list newest first", func(t *testing.T) {
+		records, err := hashi.ListAuditRecords(store.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		require.EqualValues(t, 3, records[0].Slot)
+		require.EqualValues(t, 0, records[3].Slot)
+		require.Equal(t, keyA, records[1].PublicKey)
+	})
+
+	t.Run("filter by public key and time", func(t *testing.T) {
+		records, err := hashi.ListAuditRecords(store.AuditFilter{
+			From:      start.Add(time.Hour),
+			PublicKey: "0x" + strings.ToUpper(keyA),
+		})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.EqualValues(t, 2, records[0].Slot)
+
+		records, err = hashi.ListAuditRecords(store.AuditFilter{
+			To:    start.Add(time.Hour),
+			Limit: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.EqualValues(t, 1, records[0].Slot)
+	})
+
+	t.Run("reject invalid public key filter", func(t *testing.T) {
+		_, err := hashi.ListAuditRecords(store.AuditFilter{
+			PublicKey: "aa/../config",
+		})
+		require.Equal(t, store.ErrInvalidPublicKey, err)
+	})
+
+	t.Run("prune old records", func(t *testing.T) {
+		pruned, err := hashi.PruneAuditRecords(start.Add(2 * time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 2, pruned)
+
+		records, err := hashi.ListAuditRecords(store.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+	})
+}
+
+func TestAuditRecordPaths(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	recordTime := time.Unix(1600000000, 0).UnixNano()
+
+	t.Run("invalid public key is not part of the path", func(t *testing.T) {
+		require.NoError(t, hashi.AppendAuditRecord(&store.AuditRecord{
+			Time:      recordTime,
+			PublicKey: "../config",
+			Outcome:   store.AuditOutcomeRejected,
+			RequestID: "request-0",
+		}))
+
+		keys, err := logical.CollectKeysWithPrefix(context.Background(), storage, store.AuditBase)
+		require.NoError(t, err)
+		require.Equal(t, []string{fmt.Sprintf(store.AuditPath, recordTime/int64(time.Hour), recordTime, "", "request-0")}, keys)
+
+		records, err := hashi.ListAuditRecords(store.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "../config", records[0].PublicKey)
+	})
+
+	t.Run("records at the same time are kept", func(t *testing.T) {
+		publicKey := strings.Repeat("aa", 48)
+		for _, requestID := range []string{"request-1", "request-2", ""} {
+			require.NoError(t, hashi.AppendAuditRecord(&store.AuditRecord{
+				Time:      recordTime,
+				PublicKey: publicKey,
+				Outcome:   store.AuditOutcomeSigned,
+				RequestID: requestID,
+			}))
+		}
+
+		records, err := hashi.ListAuditRecords(store.AuditFilter{PublicKey: publicKey})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+	})
+
+	t.Run("migrate records to buckets", func(t *testing.T) {
+		publicKey := strings.Repeat("cc", 48)
+		entry, err := logical.StorageEntryJSON(fmt.Sprintf("audit/%020d-%s", recordTime+1, publicKey), &store.AuditRecord{
+			Time:      recordTime + 1,
+			PublicKey: publicKey,
+			Outcome:   store.AuditOutcomeSigned,
+		})
+		require.NoError(t, err)
+		require.NoError(t, storage.Put(context.Background(), entry))
+		require.NoError(t, hashi.SetSchemaVersion(4))
+
+		_, err = hashi.Migrate(nil)
+		require.NoError(t, err)
+
+		records, err := hashi.ListAuditRecords(store.AuditFilter{PublicKey: publicKey})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		keys, err := storage.List(context.Background(), store.AuditBase)
+		require.NoError(t, err)
+		require.Equal(t, []string{fmt.Sprintf("%012d/", recordTime/int64(time.Hour))}, keys)
+	})
+}
@@ -0,0 +1,341 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+// BackupVersion is the version of the backup bundle format.
+const BackupVersion = 1
+
+// Paths
+const (
+	// BackupSlashingPath holds the latest attestation and proposal of every account at the last backup
+	BackupSlashingPath = "backup/slashing"
+)
+
+// Predefined errors
+var (
+	// ErrInvalidBackup is the error when a backup bundle can't be read or was tampered with
+	ErrInvalidBackup = errors.New("invalid backup")
+
+	// ErrSlashingHistoryRollback is the error when a restore would roll slashing history backwards
+	ErrSlashingHistoryRollback = errors.New("restore would roll slashing history backwards")
+)
+
+// backupPaths and backupPrefixes are the storage paths a backup covers.
+// Locks, indexes and the mount's encryption key are not part of a backup.
+var backupPaths = []string{
+	"config",
+	SchemaVersionPath,
+}
+
+var backupPrefixes = []string{
+	"config/",
+	"wallet/",
+	"attestations/",
+	"proposals/",
+	AccountMetadataBase,
+}
+
+// backupBundle is the at-rest representation of a backup.
+type backupBundle struct {
+	Version          int                    `json:"version"`
+	Encryptor        string                 `json:"encryptor"`
+	EncryptorVersion uint                   `json:"encryptor_version"`
+	Crypto           map[string]interface{} `json:"crypto"`
+}
+
+// backupPayload is the decrypted content of a backup.
+// Encrypted records are kept in plaintext, so they can be restored to a mount with another encryption key.
+type backupPayload struct {
+	SchemaVersion int               `json:"schema_version"`
+	Entries       map[string][]byte `json:"entries"`
+}
+
+// Backup returns the password-encrypted backup bundle of the mount.
+func (store *HashicorpVaultStore) Backup(password string) ([]byte, error) {
+	schemaVersion, err := store.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &backupPayload{
+		SchemaVersion: schemaVersion,
+		Entries:       make(map[string][]byte),
+	}
+
+	keys, err := logical.CollectKeys(store.ctx, store.storage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list records")
+	}
+
+	for _, key := range keys {
+		if !isBackupPath(key) {
+			continue
+		}
+
+		entry, err := store.storage.Get(store.ctx, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get record with path '%s'", key)
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		value := entry.Value
+		if isEncryptedPath(key) {
+			if value, err = store.decrypt(value); err != nil {
+				return nil, errors.Wrapf(err, "failed to decrypt record with path '%s'", key)
+			}
+		}
+		payload.Entries[key] = value
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal backup")
+	}
+
+	encryptor := keystorev4.New()
+	crypto, err := encryptor.Encrypt(data, password)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt backup")
+	}
+
+	ret, err := json.Marshal(&backupBundle{
+		Version:          BackupVersion,
+		Encryptor:        encryptor.Name(),
+		EncryptorVersion: encryptor.Version(),
+		Crypto:           crypto,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal backup bundle")
+	}
+
+	// Remember how far the backup covers the slashing history
+	entry, err := logical.StorageEntryJSON(BackupSlashingPath, latestSlashingRecords(keys))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal backed up slashing history")
+	}
+	if err := store.storage.Put(store.ctx, entry); err != nil {
+		return nil, errors.Wrapf(err, "failed to put record with path '%s'", BackupSlashingPath)
+	}
+
+	return ret, nil
+}
+
+// SlashingHistoryBackedUp returns true if the last backup of the mount covers its whole slashing history,
+// that is no attestation or proposal was saved since.
+func (store *HashicorpVaultStore) SlashingHistoryBackedUp() (bool, error) {
+	entry, err := store.storage.Get(store.ctx, BackupSlashingPath)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get record with path '%s'", BackupSlashingPath)
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	var backedUp map[string]uint64
+	if err := entry.DecodeJSON(&backedUp); err != nil {
+		return false, errors.Wrap(err, "failed to decode backed up slashing history")
+	}
+
+	var keys []string
+	for _, prefix := range []string{"attestations/", "proposals/"} {
+		prefixKeys, err := logical.CollectKeysWithPrefix(store.ctx, store.storage, prefix)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to list records with prefix '%s'", prefix)
+		}
+		keys = append(keys, prefixKeys...)
+	}
+
+	current := latestSlashingRecords(keys)
+	if len(current) != len(backedUp) {
+		return false, nil
+	}
+	for base, latest := range current {
+		if backup, ok := backedUp[base]; !ok || backup != latest {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Restore replaces the config, wallet and accounts of the mount with the content of the given backup bundle
+// and merges its slashing history. It refuses to restore if the mount has slashing records newer than the backup.
+func (store *HashicorpVaultStore) Restore(bundle []byte, password string) error {
+	if !store.canEncrypt() {
+		return fmt.Errorf("encryption is not enabled")
+	}
+
+	payload, err := openBackup(bundle, password)
+	if err != nil {
+		return err
+	}
+
+	if payload.SchemaVersion > CurrentSchemaVersion {
+		return errors.Wrapf(ErrInvalidBackup, "backup schema version %d is newer than the supported version %d", payload.SchemaVersion, CurrentSchemaVersion)
+	}
+
+	// Only the records a backup covers are restored, not the mount's own state such as its encryption key
+	for key := range payload.Entries {
+		if !isBackupPath(key) {
+			return errors.Wrapf(ErrInvalidBackup, "backup has record with path '%s' which is not part of a backup", key)
+		}
+	}
+
+	if err := store.checkSlashingRollback(payload.Entries); err != nil {
+		return err
+	}
+
+	// The wallet records the backup doesn't have are deleted once the backup is written,
+	// so a failed write leaves the current wallet in place
+	walletKeys, err := logical.CollectKeysWithPrefix(store.ctx, store.storage, "wallet/")
+	if err != nil {
+		return errors.Wrap(err, "failed to list wallet records")
+	}
+
+	for key, value := range payload.Entries {
+		if isEncryptedPath(key) {
+			if value, err = store.encrypt(value); err != nil {
+				return errors.Wrapf(err, "failed to encrypt record with path '%s'", key)
+			}
+		}
+
+		if err := store.storage.Put(store.ctx, &logical.StorageEntry{
+			Key:      key,
+			Value:    value,
+			SealWrap: strings.HasPrefix(key, "wallet/"),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to put record with path '%s'", key)
+		}
+	}
+
+	for _, key := range walletKeys {
+		if _, ok := payload.Entries[key]; ok {
+			continue
+		}
+		if err := store.storage.Delete(store.ctx, key); err != nil {
+			return errors.Wrapf(err, "failed to delete record with path '%s'", key)
+		}
+	}
+
+	if err := store.SetSchemaVersion(payload.SchemaVersion); err != nil {
+		return err
+	}
+
+	if _, err := store.RebuildAccountIndex(); err != nil {
+		return errors.Wrap(err, "failed to rebuild account index")
+	}
+	store.logger.Info("Restored backup", "records", len(payload.Entries), "schema_version", payload.SchemaVersion)
+
+	return store.rotateGeneration()
+}
+
+// checkSlashingRollback returns an error if the mount has an attestation or proposal
+// newer than the newest one of the same account in the backup.
+func (store *HashicorpVaultStore) checkSlashingRollback(entries map[string][]byte) error {
+	backupKeys := make([]string, 0, len(entries))
+	for key := range entries {
+		backupKeys = append(backupKeys, key)
+	}
+	backupLatest := latestSlashingRecords(backupKeys)
+
+	for _, prefix := range []string{"attestations/", "proposals/"} {
+		keys, err := logical.CollectKeysWithPrefix(store.ctx, store.storage, prefix)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list records with prefix '%s'", prefix)
+		}
+
+		for base, latest := range latestSlashingRecords(keys) {
+			if backup, ok := backupLatest[base]; !ok || backup < latest {
+				return errors.Wrapf(ErrSlashingHistoryRollback, "%s has record %d newer than the backup", base, latest)
+			}
+		}
+	}
+
+	return nil
+}
+
+// latestSlashingRecords returns the highest epoch or slot per attestations/proposals base path.
+func latestSlashingRecords(keys []string) map[string]uint64 {
+	ret := make(map[string]uint64)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "attestations/") && !strings.HasPrefix(key, "proposals/") {
+			continue
+		}
+
+		i := strings.LastIndex(key, "/")
+		number, err := strconv.ParseUint(key[i+1:], 10, 64)
+		if err != nil {
+			// latest attestation record
+			continue
+		}
+
+		base := key[:i+1]
+		if latest, ok := ret[base]; !ok || number > latest {
+			ret[base] = number
+		}
+	}
+
+	return ret
+}
+
+func openBackup(bundle []byte, password string) (*backupPayload, error) {
+	var backup backupBundle
+	if err := json.Unmarshal(bundle, &backup); err != nil || backup.Crypto == nil {
+		return nil, errors.Wrap(ErrInvalidBackup, "failed to parse backup")
+	}
+
+	if backup.Version != BackupVersion {
+		return nil, errors.Wrapf(ErrInvalidBackup, "unsupported backup version %d", backup.Version)
+	}
+
+	encryptor := keystorev4.New()
+	if backup.Encryptor != encryptor.Name() || backup.EncryptorVersion != encryptor.Version() {
+		return nil, errors.Wrapf(ErrInvalidBackup, "unsupported encryptor %s v%d", backup.Encryptor, backup.EncryptorVersion)
+	}
+
+	// The keystore checksum verifies both the password and the integrity of the backup
+	data, err := encryptor.Decrypt(backup.Crypto, password)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidBackup, "failed to decrypt backup")
+	}
+
+	var ret backupPayload
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, errors.Wrap(ErrInvalidBackup, "failed to parse backup content")
+	}
+
+	return &ret, nil
+}
+
+// isEncryptedPath returns true if the records at the given path are encrypted with the mount's key.
+func isEncryptedPath(key string) bool {
+	return key == WalletSeedPath || strings.HasPrefix(key, AccountBase)
+}
+
+// isBackupPath returns true if the record at the given path is part of a backup.
+func isBackupPath(key string) bool {
+	for _, path := range backupPaths {
+		if key == path {
+			return true
+		}
+	}
+	for _, prefix := range backupPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func backupAttestation(epoch uint64) *core.BeaconAttestation {
+	return &core.BeaconAttestation{
+		Slot:            epoch * 32,
+		BeaconBlockRoot: []byte{1, 2, 3},
+		CommitteeIndex:  1,
+		Source: &core.Checkpoint{
+			Root:  []byte{1, 2, 3},
+			Epoch: epoch - 1,
+		},
+		Target: &core.Checkpoint{
+			Root:  []byte{1, 2, 3},
+			Epoch: epoch,
+		},
+	}
+}
+
+func baseBackupStore(t *testing.T) (*store.HashicorpVaultStore, []core.ValidatorAccount) {
+	inMem, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+
+	hashi, err := store.FromInMemoryStore(context.Background(), inMem, &logical.InmemStorage{})
+	require.NoError(t, err)
+	require.NoError(t, hashi.SaveAttestation(accounts[0].ValidatorPublicKey(), backupAttestation(10)))
+	require.NoError(t, hashi.SaveProposal(accounts[0].ValidatorPublicKey(), &core.BeaconBlockHeader{
+		Slot:          100,
+		ProposerIndex: 1,
+		BodyRoot:      []byte{1, 2, 3},
+		ParentRoot:    []byte{1, 2, 3},
+		StateRoot:     []byte{1, 2, 3},
+	}))
+
+	return hashi, accounts
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	hashi, accounts := baseBackupStore(t)
+	backup, err := hashi.Backup("password")
+	require.NoError(t, err)
+
+	// restore to a mount with another encryption key and a stale account
+	storage := &logical.InmemStorage{}
+	target := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, target.EnableEncryption())
+	require.NoError(t, storage.Put(context.Background(), &logical.StorageEntry{Key: "wallet/accounts/stale", Value: []byte("stale")}))
+	require.NoError(t, target.Restore(backup, "password"))
+
+	stale, err := storage.Get(context.Background(), "wallet/accounts/stale")
+	require.NoError(t, err)
+	require.Nil(t, stale)
+
+	for _, account := range accounts {
+		res, err := target.OpenAccount(account.ID())
+		require.NoError(t, err)
+		require.Equal(t, account.ValidatorPublicKey().Marshal(), res.ValidatorPublicKey().Marshal())
+	}
+
+	wallet, err := target.OpenWallet()
+	require.NoError(t, err)
+	require.NotNil(t, wallet)
+
+	attestations, err := target.ListAllAttestations(accounts[0].ValidatorPublicKey())
+	require.NoError(t, err)
+	require.Len(t, attestations, 1)
+
+	proposals, err := target.ListAllProposals(accounts[0].ValidatorPublicKey())
+	require.NoError(t, err)
+	require.Len(t, proposals, 1)
+
+	version, err := target.SchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, store.CurrentSchemaVersion, version)
+}
+
+func TestRestoreInvalidBackup(t *testing.T) {
+	hashi, _ := baseBackupStore(t)
+	backup, err := hashi.Backup("password")
+	require.NoError(t, err)
+
+	target := store.NewHashicorpVaultStore(context.Background(), &logical.InmemStorage{}, core.TestNetwork)
+	require.NoError(t, target.EnableEncryption())
+
+	t.Run("wrong password", func(t *testing.T) {
+		err := target.Restore(backup, "wrong")
+		require.Equal(t, store.ErrInvalidBackup, errors.Cause(err))
+	})
+
+	t.Run("tampered backup", func(t *testing.T) {
+		var bundle map[string]interface{}
+		require.NoError(t, json.Unmarshal(backup, &bundle))
+		cipher := bundle["crypto"].(map[string]interface{})["cipher"].(map[string]interface{})
+		message := []byte(cipher["message"].(string))
+		if message[0] == 'a' {
+			message[0] = 'b'
+		} else {
+			message[0] = 'a'
+		}
+		cipher["message"] = string(message)
+		tampered, err := json.Marshal(bundle)
+		require.NoError(t, err)
+
+		err = target.Restore(tampered, "password")
+		require.Equal(t, store.ErrInvalidBackup, errors.Cause(err))
+	})
+
+	t.Run("malformed backup", func(t *testing.T) {
+		err := target.Restore([]byte("backup"), "password")
+		require.Equal(t, store.ErrInvalidBackup, errors.Cause(err))
+	})
+}
+
+func TestRestoreSlashingHistoryRollback(t *testing.T) {
+	hashi, accounts := baseBackupStore(t)
+	backup, err := hashi.Backup("password")
+	require.NoError(t, err)
+
+	// the mount signed after the backup was taken
+	require.NoError(t, hashi.SaveAttestation(accounts[0].ValidatorPublicKey(), backupAttestation(11)))
+
+	err = hashi.Restore(backup, "password")
+	require.Equal(t, store.ErrSlashingHistoryRollback, errors.Cause(err))
+
+	// the history is untouched
+	attestations, err := hashi.ListAllAttestations(accounts[0].ValidatorPublicKey())
+	require.NoError(t, err)
+	require.Len(t, attestations, 2)
+
+	// a fresh backup restores fine
+	backup, err = hashi.Backup("password")
+	require.NoError(t, err)
+	require.NoError(t, hashi.Restore(backup, "password"))
+}
+
+func TestRestoreForeignRecords(t *testing.T) {
+	inMem, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+	storage := &logical.InmemStorage{}
+	hashi, err := store.FromInMemoryStore(context.Background(), inMem, storage)
+	require.NoError(t, err)
+	password := "password"
+
+	for _, key := range []string{store.EncryptionKeyPath, store.GenerationPath, "index/accounts/key", "webhooks/config", "configuration"} {
+		t.Run(key, func(t *testing.T) {
+			// a bundle encrypted with the right password, with a record the mount must keep
+			data, err := json.Marshal(map[string]interface{}{
+				"schema_version": store.CurrentSchemaVersion,
+				"entries": map[string][]byte{
+					key: []byte("overwritten"),
+				},
+			})
+			require.NoError(t, err)
+			encryptor := keystorev4.New()
+			crypto, err := encryptor.Encrypt(data, password)
+			require.NoError(t, err)
+			bundle, err := json.Marshal(map[string]interface{}{
+				"version":           store.BackupVersion,
+				"encryptor":         encryptor.Name(),
+				"encryptor_version": encryptor.Version(),
+				"crypto":            crypto,
+			})
+			require.NoError(t, err)
+
+			before, err := storage.Get(context.Background(), key)
+			require.NoError(t, err)
+
+			err = hashi.Restore(bundle, password)
+			require.Equal(t, store.ErrInvalidBackup, errors.Cause(err))
+
+			after, err := storage.Get(context.Background(), key)
+			require.NoError(t, err)
+			require.Equal(t, before, after)
+
+			// the accounts are still readable
+			_, err = hashi.OpenAccount(accounts[0].ID())
+			require.NoError(t, err)
+		})
+	}
+}
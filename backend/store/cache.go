@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/wallet_nd"
+)
+
+// Paths
+const (
+	GenerationPath = "generation"
+)
+
+// Cache holds decoded records shared between requests, keyed by their storage path.
+// Entries belong to a single storage generation. Every write of the wallet, an account
+// or the config starts a new generation, which drops the entries of the previous one.
+type Cache struct {
+	lock       sync.RWMutex
+	generation string
+	entries    map[string]interface{}
+}
+
+// NewCache is the constructor of Cache.
+func NewCache() *Cache {
+	return &Cache{
+		entries: make(map[string]interface{}),
+	}
+}
+
+// Sync reads the storage generation and purges the cache if it changed.
+// It returns the current generation. Nothing is cached for an empty generation.
+func (cache *Cache) Sync(ctx context.Context, storage logical.Storage) (string, error) {
+	entry, err := storage.Get(ctx, GenerationPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get storage generation")
+	}
+
+	var generation string
+	if entry != nil {
+		generation = string(entry.Value)
+	}
+
+	cache.lock.RLock()
+	synced := cache.generation == generation
+	cache.lock.RUnlock()
+
+	if !synced {
+		cache.lock.Lock()
+		cache.generation = generation
+		cache.entries = make(map[string]interface{})
+		cache.lock.Unlock()
+	}
+
+	return generation, nil
+}
+
+// Get returns the entry of the given key if it was cached in the given generation.
+func (cache *Cache) Get(generation string, key string) (interface{}, bool) {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+
+	if len(generation) == 0 || cache.generation != generation {
+		return nil, false
+	}
+
+	value, ok := cache.entries[key]
+	return value, ok
+}
+
+// Put caches the entry of the given key. The entry is dropped if the generation changed
+// since it was read.
+func (cache *Cache) Put(generation string, key string, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if len(generation) == 0 || cache.generation != generation {
+		return
+	}
+
+	cache.entries[key] = value
+}
+
+// Invalidate drops the entry of the given storage key.
+// A change of the generation drops all entries.
+func (cache *Cache) Invalidate(key string) {
+	if key == GenerationPath {
+		cache.Purge()
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	delete(cache.entries, key)
+}
+
+// Purge drops all entries.
+func (cache *Cache) Purge() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.generation = ""
+	cache.entries = make(map[string]interface{})
+}
+
+// Len returns the number of cached entries.
+func (cache *Cache) Len() int {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+
+	return len(cache.entries)
+}
+
+// RotateGeneration starts a new storage generation. It invalidates the cached
+// records of every backend reading the given storage.
+func RotateGeneration(ctx context.Context, storage logical.Storage) error {
+	return storage.Put(ctx, &logical.StorageEntry{
+		Key:   GenerationPath,
+		Value: []byte(uuid.New().String()),
+	})
+}
+
+// SetCache makes the store read the wallet, its accounts and the encryption key through
+// the given cache. The generation is the one returned by Cache.Sync for the request.
+// The cached objects are shared, so only stores that don't change the wallet should use it.
+func (store *HashicorpVaultStore) SetCache(cache *Cache, generation string) {
+	store.cache = cache
+	store.generation = generation
+}
+
+// cached returns the cached entry of the given storage key.
+func (store *HashicorpVaultStore) cached(key string) (interface{}, bool) {
+	if store.cache == nil {
+		return nil, false
+	}
+	return store.cache.Get(store.generation, key)
+}
+
+// cacheEntry caches the entry of the given storage key.
+func (store *HashicorpVaultStore) cacheEntry(key string, value interface{}) {
+	if store.cache == nil {
+		return
+	}
+	store.cache.Put(store.generation, key, value)
+}
+
+// rotateGeneration starts a new storage generation after a write.
+func (store *HashicorpVaultStore) rotateGeneration() error {
+	if store.cache != nil {
+		store.cache.Purge()
+	}
+
+	if err := RotateGeneration(store.ctx, store.storage); err != nil {
+		return errors.Wrap(err, "failed to rotate storage generation")
+	}
+
+	store.logger.Trace("Rotated storage generation")
+	return nil
+}
+
+// bindWallet returns a copy of the cached wallet bound to the store.
+func (store *HashicorpVaultStore) bindWallet(wallet core.Wallet) core.Wallet {
+	var ret core.Wallet
+	switch w := wallet.(type) {
+	case *wallet_hd.HDWallet:
+		copied := *w
+		ret = &copied
+	case *wallet_nd.NDWallet:
+		copied := *w
+		ret = &copied
+	default:
+		return nil
+	}
+
+	ret.SetContext(store.freshContext())
+	return ret
+}
+
+// bindAccount returns a copy of the cached account bound to the store.
+func (store *HashicorpVaultStore) bindAccount(account core.ValidatorAccount) core.ValidatorAccount {
+	var ret core.ValidatorAccount
+	switch a := account.(type) {
+	case *wallet_hd.HDAccount:
+		copied := *a
+		ret = &copied
+	case *wallet_nd.NDAccount:
+		copied := *a
+		ret = &copied
+	default:
+		return nil
+	}
+
+	ret.SetContext(store.freshContext())
+	return ret
+}
@@ -0,0 +1,112 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestCacheGeneration(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	cache := store.NewCache()
+
+	t.Run("nothing is cached without generation", func(t *testing.T) {
+		generation, err := cache.Sync(ctx, storage)
+		require.NoError(t, err)
+		require.Empty(t, generation)
+
+		cache.Put(generation, "key", "value")
+		_, ok := cache.Get(generation, "key")
+		require.False(t, ok)
+	})
+
+	t.Run("entries are dropped on rotation", func(t *testing.T) {
+		require.NoError(t, store.RotateGeneration(ctx, storage))
+		generation, err := cache.Sync(ctx, storage)
+		require.NoError(t, err)
+		require.NotEmpty(t, generation)
+
+		cache.Put(generation, "key", "value")
+		value, ok := cache.Get(generation, "key")
+		require.True(t, ok)
+		require.Equal(t, "value", value)
+
+		require.NoError(t, store.RotateGeneration(ctx, storage))
+		newGeneration, err := cache.Sync(ctx, storage)
+		require.NoError(t, err)
+		require.NotEqual(t, generation, newGeneration)
+		require.Zero(t, cache.Len())
+
+		// entries read in the old generation are not cached
+		cache.Put(generation, "key", "value")
+		_, ok = cache.Get(newGeneration, "key")
+		require.False(t, ok)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		generation, err := cache.Sync(ctx, storage)
+		require.NoError(t, err)
+
+		cache.Put(generation, "a", 1)
+		cache.Put(generation, "b", 2)
+		cache.Invalidate("a")
+		require.Equal(t, 1, cache.Len())
+
+		cache.Invalidate(store.GenerationPath)
+		require.Zero(t, cache.Len())
+	})
+}
+
+func TestCachedStore(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+	account := accounts[0]
+
+	writer := store.NewHashicorpVaultStore(ctx, storage, core.TestNetwork)
+	require.NoError(t, writer.EnableEncryption())
+	require.NoError(t, writer.SaveAccount(account))
+
+	cache := store.NewCache()
+	openCached := func() *store.HashicorpVaultStore {
+		generation, err := cache.Sync(ctx, storage)
+		require.NoError(t, err)
+
+		reader := store.NewHashicorpVaultStore(ctx, storage, core.TestNetwork)
+		reader.SetCache(cache, generation)
+		require.NoError(t, reader.EnableEncryption())
+		return reader
+	}
+
+	t.Run("account is read once", func(t *testing.T) {
+		opened, err := openCached().OpenAccount(account.ID())
+		require.NoError(t, err)
+		require.Equal(t, account.ValidatorPublicKey().Marshal(), opened.ValidatorPublicKey().Marshal())
+
+		// remove the record behind the cache's back
+		require.NoError(t, storage.Delete(ctx, fmt.Sprintf(store.AccountPath, account.ID())))
+
+		opened, err = openCached().OpenAccount(account.ID())
+		require.NoError(t, err)
+		require.NotNil(t, opened)
+		require.Equal(t, account.ValidatorPublicKey().Marshal(), opened.ValidatorPublicKey().Marshal())
+	})
+
+	t.Run("writes invalidate the cache", func(t *testing.T) {
+		require.NoError(t, writer.DeleteAccount(account.ID()))
+
+		opened, err := openCached().OpenAccount(account.ID())
+		require.NoError(t, err)
+		require.Nil(t, opened)
+	})
+}
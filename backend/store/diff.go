@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/stores/in_memory"
+	"github.com/pkg/errors"
+)
+
+// StorageDiff describes the changes of replacing the wallet with an in-memory store.
+// Accounts are identified by their HEX encoded validation public key.
+type StorageDiff struct {
+	Added     []string
+	Removed   []string
+	Unchanged []string
+
+	// LostSlashingHistory lists the removed accounts that have slashing history on the mount.
+	LostSlashingHistory []string
+}
+
+// DiffInMemoryStore returns the changes FromInMemoryStore would make with the given in-memory store.
+// Nothing is written.
+func (store *HashicorpVaultStore) DiffInMemoryStore(inMem *in_memory.InMemStore) (*StorageDiff, error) {
+	incoming, err := inMem.OpenWallet()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open incoming wallet")
+	}
+
+	var current []core.ValidatorAccount
+	if wallet, err := store.OpenWallet(); err == nil {
+		current = wallet.Accounts()
+	} else if err != ErrWalletNotFound {
+		return nil, errors.Wrap(err, "failed to open wallet")
+	}
+
+	incomingKeys := accountPublicKeys(incoming.Accounts())
+	currentKeys := accountPublicKeys(current)
+
+	ret := &StorageDiff{
+		Added:               []string{},
+		Removed:             []string{},
+		Unchanged:           []string{},
+		LostSlashingHistory: []string{},
+	}
+	for key := range incomingKeys {
+		if currentKeys[key] {
+			ret.Unchanged = append(ret.Unchanged, key)
+		} else {
+			ret.Added = append(ret.Added, key)
+		}
+	}
+
+	for key := range currentKeys {
+		if incomingKeys[key] {
+			continue
+		}
+		ret.Removed = append(ret.Removed, key)
+
+		hasHistory, err := store.hasSlashingHistory(key)
+		if err != nil {
+			return nil, err
+		}
+		if hasHistory {
+			ret.LostSlashingHistory = append(ret.LostSlashingHistory, key)
+		}
+	}
+
+	sort.Strings(ret.Added)
+	sort.Strings(ret.Removed)
+	sort.Strings(ret.Unchanged)
+	sort.Strings(ret.LostSlashingHistory)
+	return ret, nil
+}
+
+// hasSlashingHistory returns true if there are attestations or proposals of the given public key.
+func (store *HashicorpVaultStore) hasSlashingHistory(publicKey string) (bool, error) {
+	for _, base := range []string{WalletAttestationsBase, WalletProposalsBase} {
+		path := fmt.Sprintf(base, publicKey)
+		keys, err := store.storage.List(store.ctx, path)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to list records with path '%s'", path)
+		}
+
+		if len(keys) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func accountPublicKeys(accounts []core.ValidatorAccount) map[string]bool {
+	ret := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		ret[hex.EncodeToString(account.ValidatorPublicKey().Marshal())] = true
+	}
+	return ret
+}
@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestDiffInMemoryStore(t *testing.T) {
+	inMem, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+	otherInMem, _, otherAccounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f00"),
+		t,
+	)
+
+	t.Run("empty mount", func(t *testing.T) {
+		hashi := store.NewHashicorpVaultStore(context.Background(), &logical.InmemStorage{}, inMem.Network())
+		require.NoError(t, hashi.EnableEncryption())
+
+		diff, err := hashi.DiffInMemoryStore(inMem)
+		require.NoError(t, err)
+		require.Len(t, diff.Added, 2)
+		require.Empty(t, diff.Removed)
+		require.Empty(t, diff.Unchanged)
+	})
+
+	t.Run("same accounts", func(t *testing.T) {
+		hashi, err := store.FromInMemoryStore(context.Background(), inMem, &logical.InmemStorage{})
+		require.NoError(t, err)
+
+		diff, err := hashi.DiffInMemoryStore(inMem)
+		require.NoError(t, err)
+		require.Empty(t, diff.Added)
+		require.Empty(t, diff.Removed)
+		require.Len(t, diff.Unchanged, 2)
+	})
+
+	t.Run("replaced accounts", func(t *testing.T) {
+		storage := &logical.InmemStorage{}
+		hashi, err := store.FromInMemoryStore(context.Background(), inMem, storage)
+		require.NoError(t, err)
+		require.NoError(t, hashi.SaveAttestation(accounts[0].ValidatorPublicKey(), backupAttestation(10)))
+
+		diff, err := hashi.DiffInMemoryStore(otherInMem)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{
+			hex.EncodeToString(otherAccounts[0].ValidatorPublicKey().Marshal()),
+			hex.EncodeToString(otherAccounts[1].ValidatorPublicKey().Marshal()),
+		}, diff.Added)
+		require.Len(t, diff.Removed, 2)
+		require.Empty(t, diff.Unchanged)
+		require.Equal(t, []string{hex.EncodeToString(accounts[0].ValidatorPublicKey().Marshal())}, diff.LostSlashingHistory)
+
+		// nothing was written
+		res, err := hashi.OpenAccount(accounts[0].ID())
+		require.NoError(t, err)
+		require.NotNil(t, res)
+	})
+}
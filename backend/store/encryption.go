@@ -0,0 +1,194 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+// Paths
+const (
+	EncryptionKeyPath = "encryption/key"
+)
+
+// encryptionKeyLength is the length in bytes of the plugin-managed encryption key.
+const encryptionKeyLength = 32
+
+// encryptionPasswordInfo binds the derived password to its purpose.
+var encryptionPasswordInfo = []byte("key-vault/accounts")
+
+// encryptedData is the at-rest representation of an encrypted record.
+type encryptedData struct {
+	Encryptor string                 `json:"encryptor"`
+	Version   uint                   `json:"version"`
+	Crypto    map[string]interface{} `json:"crypto"`
+}
+
+// EnableEncryption sets up the store to encrypt account secrets with keystorev4
+// under a password derived from the mount's encryption key.
+// The key is generated and stored on first use.
+func (store *HashicorpVaultStore) EnableEncryption() error {
+	if cached, ok := store.cached(EncryptionKeyPath); ok {
+		store.SetEncryptor(keystorev4.New(), cached.([]byte))
+		return nil
+	}
+
+	key, err := store.loadOrCreateEncryptionKey()
+	if err != nil {
+		return errors.Wrap(err, "failed to load encryption key")
+	}
+
+	password := encryptionPassword(key)
+	store.SetEncryptor(keystorev4.New(), password)
+	store.cacheEntry(EncryptionKeyPath, password)
+	return nil
+}
+
+// EncryptAccounts re-writes every plaintext account record in encrypted form
+// and seal-wraps the wallet record. It returns the number of converted accounts.
+func (store *HashicorpVaultStore) EncryptAccounts() (int, error) {
+	if !store.canEncrypt() {
+		return 0, fmt.Errorf("encryption is not enabled")
+	}
+
+	accountIDs, err := store.storage.List(store.ctx, AccountBase)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list accounts")
+	}
+
+	var converted int
+	for _, accountID := range accountIDs {
+		path := fmt.Sprintf(AccountPath, accountID)
+		entry, err := store.storage.Get(store.ctx, path)
+		if err != nil {
+			return converted, errors.Wrapf(err, "failed to get record with path '%s'", path)
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		if _, encrypted := parseEncryptedData(entry.Value); encrypted {
+			continue
+		}
+
+		data, err := store.encrypt(entry.Value)
+		if err != nil {
+			return converted, errors.Wrapf(err, "failed to encrypt record with path '%s'", path)
+		}
+
+		if err := store.storage.Put(store.ctx, &logical.StorageEntry{
+			Key:      path,
+			Value:    data,
+			SealWrap: true,
+		}); err != nil {
+			return converted, errors.Wrapf(err, "failed to put record with path '%s'", path)
+		}
+		converted++
+	}
+
+	// Re-write wallet with seal wrapping
+	entry, err := store.storage.Get(store.ctx, WalletDataPath)
+	if err != nil {
+		return converted, errors.Wrap(err, "failed to get wallet record")
+	}
+
+	if entry != nil {
+		entry.SealWrap = true
+		if err := store.storage.Put(store.ctx, entry); err != nil {
+			return converted, errors.Wrap(err, "failed to put wallet record")
+		}
+	}
+
+	return converted, nil
+}
+
+// encrypt encrypts the given data if an encryptor is set.
+func (store *HashicorpVaultStore) encrypt(data []byte) ([]byte, error) {
+	if !store.canEncrypt() {
+		return data, nil
+	}
+
+	crypto, err := store.encryptor.Encrypt(data, string(store.encryptionPassword))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt data")
+	}
+
+	return json.Marshal(&encryptedData{
+		Encryptor: store.encryptor.Name(),
+		Version:   store.encryptor.Version(),
+		Crypto:    crypto,
+	})
+}
+
+// decrypt decrypts the given data. Plaintext data written before
+// the encryption was enabled is returned as is.
+func (store *HashicorpVaultStore) decrypt(data []byte) ([]byte, error) {
+	encrypted, ok := parseEncryptedData(data)
+	if !ok {
+		return data, nil
+	}
+
+	if !store.canEncrypt() {
+		return nil, fmt.Errorf("record is encrypted but encryption is not enabled")
+	}
+
+	if encrypted.Encryptor != store.encryptor.Name() || encrypted.Version != store.encryptor.Version() {
+		return nil, fmt.Errorf("unsupported encryptor %s v%d", encrypted.Encryptor, encrypted.Version)
+	}
+
+	ret, err := store.encryptor.Decrypt(encrypted.Crypto, string(store.encryptionPassword))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt data")
+	}
+
+	return ret, nil
+}
+
+func (store *HashicorpVaultStore) loadOrCreateEncryptionKey() ([]byte, error) {
+	entry, err := store.storage.Get(store.ctx, EncryptionKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil {
+		return entry.Value, nil
+	}
+
+	key := make([]byte, encryptionKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate encryption key")
+	}
+
+	if err := store.storage.Put(store.ctx, &logical.StorageEntry{
+		Key:      EncryptionKeyPath,
+		Value:    key,
+		SealWrap: true,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to store encryption key")
+	}
+
+	return key, nil
+}
+
+func encryptionPassword(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(encryptionPasswordInfo)
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+func parseEncryptedData(data []byte) (*encryptedData, bool) {
+	var ret encryptedData
+	if err := json.Unmarshal(data, &ret); err != nil || ret.Crypto == nil {
+		return nil, false
+	}
+
+	return &ret, true
+}
@@ -0,0 +1,88 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestAccountEncryptedAtRest(t *testing.T) {
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+	account := accounts[0]
+
+	storage := &logical.InmemStorage{}
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+	require.NoError(t, hashi.SaveAccount(account))
+
+	// raw record must not contain the private key
+	entry, err := storage.Get(context.Background(), fmt.Sprintf(store.AccountPath, account.ID().String()))
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(entry.Value, &raw))
+	require.Contains(t, raw, "crypto")
+	require.NotContains(t, raw, "validationKey")
+
+	// the same mount key decrypts the account
+	reopened := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, reopened.EnableEncryption())
+	res, err := reopened.OpenAccount(account.ID())
+	require.NoError(t, err)
+	require.Equal(t, account.ValidatorPublicKey().Marshal(), res.ValidatorPublicKey().Marshal())
+
+	// without encryption the record can't be read
+	plain := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	_, err = plain.OpenAccount(account.ID())
+	require.Error(t, err)
+}
+
+func TestEncryptAccounts(t *testing.T) {
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+
+	// store plaintext accounts
+	storage := &logical.InmemStorage{}
+	plain := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	for _, account := range accounts {
+		require.NoError(t, plain.SaveAccount(account))
+	}
+
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+
+	// plaintext accounts are still readable before the migration
+	res, err := hashi.OpenAccount(accounts[0].ID())
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	converted, err := hashi.EncryptAccounts()
+	require.NoError(t, err)
+	require.Equal(t, len(accounts), converted)
+
+	// running again is a no-op
+	converted, err = hashi.EncryptAccounts()
+	require.NoError(t, err)
+	require.Zero(t, converted)
+
+	for _, account := range accounts {
+		res, err := hashi.OpenAccount(account.ID())
+		require.NoError(t, err)
+		require.Equal(t, account.ValidatorPublicKey().Marshal(), res.ValidatorPublicKey().Marshal())
+
+		_, err = plain.OpenAccount(account.ID())
+		require.Error(t, err)
+	}
+}
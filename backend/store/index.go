@@ -0,0 +1,228 @@
+package store
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// Paths
+const (
+	AccountIndexBase = "index/accounts/"
+	AccountIndexPath = AccountIndexBase + "%s" // account public key
+)
+
+// AccountIndexReport is the result of the account index consistency check.
+// Public keys are HEX encoded and sorted.
+type AccountIndexReport struct {
+	// Accounts is the number of stored accounts
+	Accounts int
+
+	// Missing are the accounts without index entry
+	Missing []string
+
+	// Dangling are the index entries of accounts that don't exist
+	Dangling []string
+
+	// Mismatched are the index entries pointing to another account
+	Mismatched []string
+}
+
+// Consistent returns true if every account is indexed correctly.
+func (report *AccountIndexReport) Consistent() bool {
+	return len(report.Missing) == 0 && len(report.Dangling) == 0 && len(report.Mismatched) == 0
+}
+
+// AccountByPublicKey opens the account with the given HEX encoded public key using the account index.
+// Returns wallet_hd.ErrAccountNotFound if the account is not indexed.
+func (store *HashicorpVaultStore) AccountByPublicKey(publicKey string) (core.ValidatorAccount, error) {
+	accountID, err := store.indexedAccountID(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := store.OpenAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account == nil {
+		return nil, wallet_hd.ErrAccountNotFound
+	}
+
+	return account, nil
+}
+
+// ListAccountPublicKeys returns the sorted HEX encoded public keys of the indexed accounts.
+func (store *HashicorpVaultStore) ListAccountPublicKeys() ([]string, error) {
+	publicKeys, err := store.storage.List(store.ctx, AccountIndexBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list account index")
+	}
+
+	sort.Strings(publicKeys)
+	return publicKeys, nil
+}
+
+// RebuildAccountIndex re-creates the account index from the stored accounts.
+// It returns the number of indexed accounts.
+func (store *HashicorpVaultStore) RebuildAccountIndex() (int, error) {
+	if err := store.clearAccountIndex(); err != nil {
+		return 0, err
+	}
+
+	accounts, err := store.storedAccounts()
+	if err != nil {
+		return 0, err
+	}
+
+	for publicKey, accountID := range accounts {
+		if err := store.indexAccount(publicKey, accountID); err != nil {
+			return 0, err
+		}
+	}
+	store.logger.Info("Rebuilt account index", "accounts", len(accounts))
+
+	return len(accounts), nil
+}
+
+// CheckAccountIndex compares the account index with the stored accounts.
+func (store *HashicorpVaultStore) CheckAccountIndex() (*AccountIndexReport, error) {
+	accounts, err := store.storedAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	indexed, err := store.storage.List(store.ctx, AccountIndexBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list account index")
+	}
+
+	report := &AccountIndexReport{
+		Accounts:   len(accounts),
+		Missing:    []string{},
+		Dangling:   []string{},
+		Mismatched: []string{},
+	}
+
+	for _, publicKey := range indexed {
+		accountID, err := store.indexedAccountID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		expected, ok := accounts[publicKey]
+		switch {
+		case !ok:
+			report.Dangling = append(report.Dangling, publicKey)
+		case expected != accountID:
+			report.Mismatched = append(report.Mismatched, publicKey)
+		}
+	}
+
+	isIndexed := make(map[string]bool, len(indexed))
+	for _, publicKey := range indexed {
+		isIndexed[publicKey] = true
+	}
+	for publicKey := range accounts {
+		if !isIndexed[publicKey] {
+			report.Missing = append(report.Missing, publicKey)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Dangling)
+	sort.Strings(report.Mismatched)
+	return report, nil
+}
+
+// indexedAccountID returns the account ID of the given HEX encoded public key.
+func (store *HashicorpVaultStore) indexedAccountID(publicKey string) (uuid.UUID, error) {
+	path := fmt.Sprintf(AccountIndexPath, publicKey)
+	if cached, ok := store.cached(path); ok {
+		return cached.(uuid.UUID), nil
+	}
+
+	entry, err := store.storage.Get(store.ctx, path)
+	if err != nil {
+		return uuid.UUID{}, errors.Wrapf(err, "failed to get record with path '%s'", path)
+	}
+
+	if entry == nil {
+		return uuid.UUID{}, wallet_hd.ErrAccountNotFound
+	}
+
+	accountID, err := uuid.ParseBytes(entry.Value)
+	if err != nil {
+		return uuid.UUID{}, errors.Wrapf(err, "invalid account ID in record with path '%s'", path)
+	}
+	store.cacheEntry(path, accountID)
+
+	return accountID, nil
+}
+
+// indexAccount stores the account index entry of the given HEX encoded public key.
+func (store *HashicorpVaultStore) indexAccount(publicKey string, accountID uuid.UUID) error {
+	return store.storage.Put(store.ctx, &logical.StorageEntry{
+		Key:   fmt.Sprintf(AccountIndexPath, publicKey),
+		Value: []byte(accountID.String()),
+	})
+}
+
+// unindexAccount deletes the account index entry of the given account.
+func (store *HashicorpVaultStore) unindexAccount(account core.ValidatorAccount) error {
+	publicKey := hex.EncodeToString(account.ValidatorPublicKey().Marshal())
+	return store.storage.Delete(store.ctx, fmt.Sprintf(AccountIndexPath, publicKey))
+}
+
+// clearAccountIndex deletes all account index entries.
+func (store *HashicorpVaultStore) clearAccountIndex() error {
+	indexed, err := store.storage.List(store.ctx, AccountIndexBase)
+	if err != nil {
+		return errors.Wrap(err, "failed to list account index")
+	}
+
+	for _, publicKey := range indexed {
+		path := fmt.Sprintf(AccountIndexPath, publicKey)
+		if err := store.storage.Delete(store.ctx, path); err != nil {
+			return errors.Wrapf(err, "failed to delete record with path '%s'", path)
+		}
+	}
+
+	return nil
+}
+
+// storedAccounts returns the IDs of the stored accounts by HEX encoded public key.
+func (store *HashicorpVaultStore) storedAccounts() (map[string]uuid.UUID, error) {
+	accountIDs, err := store.storage.List(store.ctx, AccountBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list accounts")
+	}
+
+	ret := make(map[string]uuid.UUID, len(accountIDs))
+	for _, accountID := range accountIDs {
+		id, err := uuid.Parse(accountID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid account ID %s", accountID)
+		}
+
+		account, err := store.OpenAccount(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open account %s", accountID)
+		}
+
+		if account == nil {
+			continue
+		}
+
+		ret[hex.EncodeToString(account.ValidatorPublicKey().Marshal())] = account.ID()
+	}
+
+	return ret, nil
+}
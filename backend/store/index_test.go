@@ -0,0 +1,86 @@
+package store_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestAccountIndex(t *testing.T) {
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+
+	storage := &logical.InmemStorage{}
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+	for _, account := range accounts {
+		require.NoError(t, hashi.SaveAccount(account))
+	}
+
+	publicKey := func(account core.ValidatorAccount) string {
+		return hex.EncodeToString(account.ValidatorPublicKey().Marshal())
+	}
+
+	t.Run("accounts are indexed on save", func(t *testing.T) {
+		for _, account := range accounts {
+			opened, err := hashi.AccountByPublicKey(publicKey(account))
+			require.NoError(t, err)
+			require.Equal(t, account.ID(), opened.ID())
+		}
+
+		report, err := hashi.CheckAccountIndex()
+		require.NoError(t, err)
+		require.True(t, report.Consistent())
+		require.Equal(t, 2, report.Accounts)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		_, err := hashi.AccountByPublicKey("ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3270")
+		require.Equal(t, wallet_hd.ErrAccountNotFound, err)
+	})
+
+	t.Run("accounts are removed from the index on delete", func(t *testing.T) {
+		require.NoError(t, hashi.DeleteAccount(accounts[1].ID()))
+
+		_, err := hashi.AccountByPublicKey(publicKey(accounts[1]))
+		require.Equal(t, wallet_hd.ErrAccountNotFound, err)
+
+		report, err := hashi.CheckAccountIndex()
+		require.NoError(t, err)
+		require.True(t, report.Consistent())
+		require.Equal(t, 1, report.Accounts)
+	})
+
+	t.Run("check and rebuild", func(t *testing.T) {
+		ctx := context.Background()
+		require.NoError(t, storage.Delete(ctx, fmt.Sprintf(store.AccountIndexPath, publicKey(accounts[0]))))
+		require.NoError(t, storage.Put(ctx, &logical.StorageEntry{
+			Key:   fmt.Sprintf(store.AccountIndexPath, publicKey(accounts[1])),
+			Value: []byte(accounts[1].ID().String()),
+		}))
+
+		report, err := hashi.CheckAccountIndex()
+		require.NoError(t, err)
+		require.False(t, report.Consistent())
+		require.Equal(t, []string{publicKey(accounts[0])}, report.Missing)
+		require.Equal(t, []string{publicKey(accounts[1])}, report.Dangling)
+
+		indexed, err := hashi.RebuildAccountIndex()
+		require.NoError(t, err)
+		require.Equal(t, 1, indexed)
+
+		report, err = hashi.CheckAccountIndex()
+		require.NoError(t, err)
+		require.True(t, report.Consistent())
+	})
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// Paths
+const (
+	AccountMetadataBase = "metadata/"
+	AccountMetadataPath = AccountMetadataBase + "%s" // account public key
+)
+
+// Account statuses
+const (
+	// AccountStatusActive is the status of accounts that sign
+	AccountStatusActive = "active"
+
+	// AccountStatusPaused is the status of accounts paused by an operator, they don't sign
+	AccountStatusPaused = "paused"
+)
+
+// AccountMetadata is the operator metadata of an account.
+type AccountMetadata struct {
+	Labels       map[string]string `json:"labels"`
+	FeeRecipient string            `json:"fee_recipient"`
+	Graffiti     string            `json:"graffiti"`
+	Status       string            `json:"status"`
+
+	// ImportedAt is the unix time the account was created or imported, 0 if unknown
+	ImportedAt int64 `json:"imported_at"`
+}
+
+// IsAccountStatus returns true if the given value is a known account status.
+func IsAccountStatus(status string) bool {
+	return status == AccountStatusActive || status == AccountStatusPaused
+}
+
+// MatchLabels returns true if the metadata has all the given labels.
+func (metadata *AccountMetadata) MatchLabels(labels map[string]string) bool {
+	for key, value := range labels {
+		if v, ok := metadata.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveAccountMetadata stores the metadata of the account with the given HEX encoded public key.
+func (store *HashicorpVaultStore) SaveAccountMetadata(publicKey string, metadata *AccountMetadata) error {
+	if err := store.putAccountMetadata(publicKey, metadata); err != nil {
+		return err
+	}
+
+	return store.rotateGeneration()
+}
+
+// OpenAccountMetadata returns the metadata of the account with the given HEX encoded public key.
+// Returns empty metadata if nothing was stored.
+func (store *HashicorpVaultStore) OpenAccountMetadata(publicKey string) (*AccountMetadata, error) {
+	path := fmt.Sprintf(AccountMetadataPath, publicKey)
+	if cached, ok := store.cached(path); ok {
+		return cached.(*AccountMetadata), nil
+	}
+
+	entry, err := store.storage.Get(store.ctx, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get record with path '%s'", path)
+	}
+
+	ret := &AccountMetadata{}
+	if entry != nil {
+		if err := entry.DecodeJSON(ret); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal account metadata")
+		}
+	}
+
+	if ret.Labels == nil {
+		ret.Labels = make(map[string]string)
+	}
+
+	if len(ret.Status) == 0 {
+		ret.Status = AccountStatusActive
+	}
+	store.cacheEntry(path, ret)
+
+	return ret, nil
+}
+
+func (store *HashicorpVaultStore) putAccountMetadata(publicKey string, metadata *AccountMetadata) error {
+	entry, err := logical.StorageEntryJSON(fmt.Sprintf(AccountMetadataPath, publicKey), metadata)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal account metadata")
+	}
+
+	return store.storage.Put(store.ctx, entry)
+}
+
+// recordImport stores the import time of a new account.
+func (store *HashicorpVaultStore) recordImport(publicKey string) error {
+	metadata, err := store.OpenAccountMetadata(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if metadata.ImportedAt != 0 {
+		return nil
+	}
+
+	metadata.ImportedAt = time.Now().Unix()
+	return store.putAccountMetadata(publicKey, metadata)
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// Paths
+const (
+	SchemaVersionPath = "schema/version"
+)
+
+// Migration is a single step upgrading the storage layout from Version-1 to Version.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(store *HashicorpVaultStore) error
+}
+
+// Migrations is the ordered list of storage migrations.
+// Mounts without a stored schema version are at version 0.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "encrypt account records at rest",
+		Migrate: func(store *HashicorpVaultStore) error {
+			_, err := store.EncryptAccounts()
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "store account type",
+		Migrate: func(store *HashicorpVaultStore) error {
+			return store.rewriteAccounts()
+		},
+	},
+	{
+		Version:     3,
+		Description: "store storage generation",
+		Migrate: func(store *HashicorpVaultStore) error {
+			return store.rotateGeneration()
+		},
+	},
+	{
+		Version:     4,
+		Description: "build account index",
+		Migrate: func(store *HashicorpVaultStore) error {
+			_, err := store.RebuildAccountIndex()
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "bucket audit records by hour",
+		Migrate: func(store *HashicorpVaultStore) error {
+			return store.bucketAuditRecords()
+		},
+	},
+}
+
+// CurrentSchemaVersion is the storage schema version of this build.
+var CurrentSchemaVersion = Migrations[len(Migrations)-1].Version
+
+// schemaVersion is the stored schema version record.
+type schemaVersion struct {
+	Version int `json:"version"`
+}
+
+// SchemaVersion returns the schema version of the storage.
+func (store *HashicorpVaultStore) SchemaVersion() (int, error) {
+	entry, err := store.storage.Get(store.ctx, SchemaVersionPath)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get schema version")
+	}
+
+	if entry == nil {
+		return 0, nil
+	}
+
+	var ret schemaVersion
+	if err := entry.DecodeJSON(&ret); err != nil {
+		return 0, errors.Wrap(err, "failed to decode schema version")
+	}
+
+	return ret.Version, nil
+}
+
+// SetSchemaVersion stores the schema version of the storage.
+func (store *HashicorpVaultStore) SetSchemaVersion(version int) error {
+	entry, err := logical.StorageEntryJSON(SchemaVersionPath, &schemaVersion{Version: version})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schema version")
+	}
+
+	return store.storage.Put(store.ctx, entry)
+}
+
+// Migrate runs the pending migrations in order. The schema version is stored after
+// every step, so an interrupted upgrade resumes from the last completed step.
+// The progress callback is called before each step. It returns the applied migrations.
+func (store *HashicorpVaultStore) Migrate(progress func(migration Migration)) ([]Migration, error) {
+	version, err := store.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("storage schema version %d is newer than the supported version %d", version, CurrentSchemaVersion)
+	}
+
+	var applied []Migration
+	for _, migration := range Migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		if progress != nil {
+			progress(migration)
+		}
+
+		if err := migration.Migrate(store); err != nil {
+			return applied, errors.Wrapf(err, "failed to migrate storage to schema version %d", migration.Version)
+		}
+
+		if err := store.SetSchemaVersion(migration.Version); err != nil {
+			return applied, errors.Wrapf(err, "failed to set schema version %d", migration.Version)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// rewriteAccounts re-writes every account record in the current layout.
+func (store *HashicorpVaultStore) rewriteAccounts() error {
+	accountIDs, err := store.storage.List(store.ctx, AccountBase)
+	if err != nil {
+		return errors.Wrap(err, "failed to list accounts")
+	}
+
+	for _, accountID := range accountIDs {
+		id, err := uuid.Parse(accountID)
+		if err != nil {
+			return errors.Wrapf(err, "invalid account ID %s", accountID)
+		}
+
+		account, err := store.OpenAccount(id)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open account %s", accountID)
+		}
+
+		if account == nil {
+			continue
+		}
+
+		if err := store.SaveAccount(account); err != nil {
+			return errors.Wrapf(err, "failed to save account %s", accountID)
+		}
+	}
+
+	return nil
+}
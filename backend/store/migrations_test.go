@@ -0,0 +1,69 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+func TestMigrate(t *testing.T) {
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+
+	// store plaintext accounts in the unversioned layout
+	storage := &logical.InmemStorage{}
+	plain := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	for _, account := range accounts {
+		require.NoError(t, plain.SaveAccount(account))
+	}
+
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+	version, err := hashi.SchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, 0, version)
+
+	var progress []int
+	applied, err := hashi.Migrate(func(migration store.Migration) {
+		progress = append(progress, migration.Version)
+	})
+	require.NoError(t, err)
+	require.Len(t, applied, len(store.Migrations))
+	require.Len(t, progress, len(store.Migrations))
+
+	version, err = hashi.SchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, store.CurrentSchemaVersion, version)
+
+	// accounts are in the current layout
+	for _, account := range accounts {
+		entry, err := storage.Get(context.Background(), fmt.Sprintf(store.AccountPath, account.ID().String()))
+		require.NoError(t, err)
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(entry.Value, &raw))
+		require.Contains(t, raw, "crypto")
+	}
+
+	// nothing to do the second time
+	applied, err = hashi.Migrate(nil)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	hashi := store.NewHashicorpVaultStore(context.Background(), &logical.InmemStorage{}, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+	require.NoError(t, hashi.SetSchemaVersion(store.CurrentSchemaVersion+1))
+
+	_, err := hashi.Migrate(nil)
+	require.Error(t, err)
+}
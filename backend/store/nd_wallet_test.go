@@ -0,0 +1,100 @@
+package store_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+
+	"github.com/bloxapp/key-vault/backend/store"
+	"github.com/bloxapp/key-vault/backend/wallet_nd"
+)
+
+func TestMixedWallet(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff")
+	storage := &logical.InmemStorage{}
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+
+	wallet := wallet_nd.NewNDWallet(&core.WalletContext{Storage: hashi})
+	require.NoError(t, hashi.SaveWallet(wallet))
+
+	derived, err := wallet.CreateValidatorAccount(seed, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/0", derived.BasePath())
+
+	key, err := e2types.GenerateBLSPrivateKey()
+	require.NoError(t, err)
+	imported, err := wallet_nd.NewImportedAccount("imported", key.Marshal(), nil, &core.WalletContext{Storage: hashi})
+	require.NoError(t, err)
+	require.NoError(t, wallet.AddValidatorAccount(imported))
+	require.Error(t, wallet.AddValidatorAccount(imported))
+
+	// the next derived account skips imported accounts
+	next, err := wallet.CreateValidatorAccount(seed, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/1", next.BasePath())
+
+	// reopen and decode the stored types
+	reopened := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, reopened.EnableEncryption())
+	res, err := reopened.OpenWallet()
+	require.NoError(t, err)
+	require.Equal(t, core.ND, res.Type())
+	require.Equal(t, wallet.ID(), res.ID())
+	require.Len(t, res.Accounts(), 3)
+
+	account, err := res.AccountByPublicKey(hex.EncodeToString(key.PublicKey().Marshal()))
+	require.NoError(t, err)
+	require.Equal(t, store.NDAccountType, store.AccountType(account))
+	require.Equal(t, "imported", account.Name())
+	require.Nil(t, account.WithdrawalPublicKey())
+
+	sig, err := account.ValidationKeySign([]byte("data"))
+	require.NoError(t, err)
+	require.True(t, sig.Verify([]byte("data"), key.PublicKey()))
+
+	account, err = res.AccountByPublicKey(hex.EncodeToString(derived.ValidatorPublicKey().Marshal()))
+	require.NoError(t, err)
+	require.Equal(t, store.HDAccountType, store.AccountType(account))
+	require.Equal(t, derived.WithdrawalPublicKey().Marshal(), account.WithdrawalPublicKey().Marshal())
+}
+
+func TestOpenUntypedAccount(t *testing.T) {
+	_, _, accounts := baseKeyVault(
+		_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1fff"),
+		t,
+	)
+
+	// account record written before the type was stored
+	data, err := json.Marshal(accounts[0])
+	require.NoError(t, err)
+	storage := &logical.InmemStorage{}
+	require.NoError(t, storage.Put(context.Background(), &logical.StorageEntry{
+		Key:   fmt.Sprintf(store.AccountPath, accounts[0].ID().String()),
+		Value: data,
+	}))
+
+	hashi := store.NewHashicorpVaultStore(context.Background(), storage, core.TestNetwork)
+	require.NoError(t, hashi.EnableEncryption())
+	account, err := hashi.OpenAccount(accounts[0].ID())
+	require.NoError(t, err)
+	require.Equal(t, store.HDAccountType, store.AccountType(account))
+	require.Equal(t, accounts[0].ValidatorPublicKey().Marshal(), account.ValidatorPublicKey().Marshal())
+
+	// the migration stores the type
+	_, err = hashi.Migrate(nil)
+	require.NoError(t, err)
+	entry, err := storage.Get(context.Background(), fmt.Sprintf(store.AccountPath, accounts[0].ID().String()))
+	require.NoError(t, err)
+	require.NotEqual(t, data, entry.Value)
+	account, err = hashi.OpenAccount(accounts[0].ID())
+	require.NoError(t, err)
+	require.Equal(t, accounts[0].ValidatorPublicKey().Marshal(), account.ValidatorPublicKey().Marshal())
+}
@@ -34,7 +34,12 @@ func (store *HashicorpVaultStore) SaveAttestation(key e2types.PublicKey, req *co
 		Value:    data,
 		SealWrap: false,
 	}
-	return store.storage.Put(store.ctx, entry)
+	if err := store.storage.Put(store.ctx, entry); err != nil {
+		return err
+	}
+
+	store.logger.Debug("Saved attestation", "public_key", store.identfierFromKey(key), "slot", req.Slot, "source_epoch", req.Source.Epoch, "target_epoch", req.Target.Epoch)
+	return nil
 }
 
 // RetrieveAttestation implements Storage imterface.
@@ -120,7 +125,12 @@ func (store *HashicorpVaultStore) SaveProposal(key e2types.PublicKey, req *core.
 		Value:    data,
 		SealWrap: false,
 	}
-	return store.storage.Put(store.ctx, entry)
+	if err := store.storage.Put(store.ctx, entry); err != nil {
+		return err
+	}
+
+	store.logger.Debug("Saved proposal", "public_key", store.identfierFromKey(key), "slot", req.Slot)
+	return nil
 }
 
 // RetrieveProposal implements Storage interface.
@@ -175,6 +185,33 @@ func (store *HashicorpVaultStore) ListAllProposals(key e2types.PublicKey) ([]*co
 	return proposals, nil
 }
 
+// RetrieveLatestProposal returns the proposal with the highest slot. Returns nil,nil if there is none.
+func (store *HashicorpVaultStore) RetrieveLatestProposal(key e2types.PublicKey) (*core.BeaconBlockHeader, error) {
+	path := fmt.Sprintf(WalletProposalsBase, store.identfierFromKey(key))
+	entries, err := store.storage.List(store.ctx, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list records from storage with path '%s'", path)
+	}
+
+	var latest *uint64
+	for _, entry := range entries {
+		slot, err := strconv.ParseUint(entry, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid slot number %s", entry)
+		}
+
+		if latest == nil || slot > *latest {
+			latest = &slot
+		}
+	}
+
+	if latest == nil {
+		return nil, nil
+	}
+
+	return store.RetrieveProposal(key, *latest)
+}
+
 // SaveLatestAttestation implements Storage interface.
 func (store *HashicorpVaultStore) SaveLatestAttestation(key e2types.PublicKey, req *core.BeaconAttestation) error {
 	path := fmt.Sprintf(WalletLatestAttestationPath, store.identfierFromKey(key))
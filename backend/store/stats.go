@@ -0,0 +1,172 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// Paths
+const (
+	AccountStatsBase = "stats/"
+	AccountStatsPath = AccountStatsBase + "%s" // account public key
+)
+
+// AccountStats are the signing counters of an account.
+type AccountStats struct {
+	// Signed is the number of signatures by message type
+	Signed map[string]uint64 `json:"signed"`
+
+	// Refused is the number of refused messages by outcome
+	Refused map[string]uint64 `json:"refused"`
+
+	LastAttestationSlot        uint64 `json:"last_attestation_slot"`
+	LastAttestationSourceEpoch uint64 `json:"last_attestation_source_epoch"`
+	LastAttestationTargetEpoch uint64 `json:"last_attestation_target_epoch"`
+	LastProposalSlot           uint64 `json:"last_proposal_slot"`
+
+	// Times are in nanoseconds since the Unix epoch, 0 if it never happened
+	LastAttestationTime int64 `json:"last_attestation_time"`
+	LastProposalTime    int64 `json:"last_proposal_time"`
+	LastRefusalTime     int64 `json:"last_refusal_time"`
+}
+
+// NewAccountStats is the constructor of AccountStats.
+func NewAccountStats() *AccountStats {
+	return &AccountStats{
+		Signed:  make(map[string]uint64),
+		Refused: make(map[string]uint64),
+	}
+}
+
+// Add counts the signing decision of the given audit record.
+func (stats *AccountStats) Add(record *AuditRecord) {
+	if record.Outcome != AuditOutcomeSigned {
+		stats.Refused[record.Outcome]++
+		stats.LastRefusalTime = record.Time
+		return
+	}
+
+	stats.Signed[record.MessageType]++
+	switch record.MessageType {
+	case AuditMessageAttestation:
+		stats.LastAttestationSlot = record.Slot
+		stats.LastAttestationSourceEpoch = record.SourceEpoch
+		stats.LastAttestationTargetEpoch = record.TargetEpoch
+		stats.LastAttestationTime = record.Time
+	case AuditMessageProposal:
+		stats.LastProposalSlot = record.Slot
+		stats.LastProposalTime = record.Time
+	}
+}
+
+// Merge adds the counters of the given stats and keeps the latest signatures.
+func (stats *AccountStats) Merge(other *AccountStats) {
+	for messageType, count := range other.Signed {
+		stats.Signed[messageType] += count
+	}
+	for outcome, count := range other.Refused {
+		stats.Refused[outcome] += count
+	}
+
+	if other.LastAttestationTime > stats.LastAttestationTime {
+		stats.LastAttestationSlot = other.LastAttestationSlot
+		stats.LastAttestationSourceEpoch = other.LastAttestationSourceEpoch
+		stats.LastAttestationTargetEpoch = other.LastAttestationTargetEpoch
+		stats.LastAttestationTime = other.LastAttestationTime
+	}
+	if other.LastProposalTime > stats.LastProposalTime {
+		stats.LastProposalSlot = other.LastProposalSlot
+		stats.LastProposalTime = other.LastProposalTime
+	}
+	if other.LastRefusalTime > stats.LastRefusalTime {
+		stats.LastRefusalTime = other.LastRefusalTime
+	}
+}
+
+// AddAccountStats adds the given stats to the stored stats of the account with the given HEX encoded public key.
+// Stats of unknown accounts are not stored.
+func (store *HashicorpVaultStore) AddAccountStats(publicKey string, stats *AccountStats) error {
+	if _, err := store.indexedAccountID(publicKey); err != nil {
+		if err == wallet_hd.ErrAccountNotFound {
+			return nil
+		}
+		return err
+	}
+
+	stored, err := store.OpenAccountStats(publicKey)
+	if err != nil {
+		return err
+	}
+	stored.Merge(stats)
+
+	entry, err := logical.StorageEntryJSON(fmt.Sprintf(AccountStatsPath, publicKey), stored)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal account stats")
+	}
+
+	return store.storage.Put(store.ctx, entry)
+}
+
+// OpenAccountStats returns the stats of the account with the given HEX encoded public key.
+// Returns empty stats if nothing was stored.
+func (store *HashicorpVaultStore) OpenAccountStats(publicKey string) (*AccountStats, error) {
+	path := fmt.Sprintf(AccountStatsPath, publicKey)
+	entry, err := store.storage.Get(store.ctx, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get record with path '%s'", path)
+	}
+
+	ret := NewAccountStats()
+	if entry != nil {
+		if err := entry.DecodeJSON(ret); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal account stats")
+		}
+	}
+
+	return ret, nil
+}
+
+// ListAccountStats returns the stats of all accounts by HEX encoded public key.
+func (store *HashicorpVaultStore) ListAccountStats() (map[string]*AccountStats, error) {
+	publicKeys, err := store.storage.List(store.ctx, AccountStatsBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list account stats")
+	}
+
+	ret := make(map[string]*AccountStats, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		stats, err := store.OpenAccountStats(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		ret[publicKey] = stats
+	}
+
+	return ret, nil
+}
+
+// ResetAccountStats deletes the stats of the account with the given HEX encoded public key.
+func (store *HashicorpVaultStore) ResetAccountStats(publicKey string) error {
+	path := fmt.Sprintf(AccountStatsPath, publicKey)
+	return errors.Wrapf(store.storage.Delete(store.ctx, path), "failed to delete record with path '%s'", path)
+}
+
+// ResetAllAccountStats deletes the stats of all accounts.
+// It returns the public keys of the reset accounts.
+func (store *HashicorpVaultStore) ResetAllAccountStats() ([]string, error) {
+	publicKeys, err := store.storage.List(store.ctx, AccountStatsBase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list account stats")
+	}
+
+	for _, publicKey := range publicKeys {
+		if err := store.ResetAccountStats(publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return publicKeys, nil
+}
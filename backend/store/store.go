@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
@@ -9,19 +10,45 @@ import (
 	"github.com/bloxapp/eth2-key-manager/stores/in_memory"
 	"github.com/bloxapp/eth2-key-manager/wallet_hd"
 	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/pkg/errors"
 	types "github.com/wealdtech/go-eth2-wallet-types/v2"
+
+	"github.com/bloxapp/key-vault/backend/wallet_nd"
 )
 
 // Paths
 const (
 	WalletDataPath = "wallet/data"
+	WalletSeedPath = "wallet/seed"
 
 	AccountBase = "wallet/accounts/"
 	AccountPath = AccountBase + "%s"
 )
 
+// Account types
+const (
+	// HDAccountType is the type of accounts derived from a seed
+	HDAccountType = "HD"
+
+	// NDAccountType is the type of individually imported accounts
+	NDAccountType = "ND"
+)
+
+// accountRecord is the stored account with its type.
+// Records written before the type was stored hold the HD account only.
+type accountRecord struct {
+	Type    string          `json:"type"`
+	Account json.RawMessage `json:"account"`
+}
+
+// Predefined errors
+var (
+	// ErrWalletNotFound is the error when wallet not found
+	ErrWalletNotFound = errors.New("wallet not found")
+)
+
 // HashicorpVaultStore implements store.Store interface using Vault.
 type HashicorpVaultStore struct {
 	storage logical.Storage
@@ -30,6 +57,12 @@ type HashicorpVaultStore struct {
 
 	encryptor          types.Encryptor
 	encryptionPassword []byte
+
+	cache      *Cache
+	generation string
+
+	// logger never receives key material, accounts are logged by ID and public key
+	logger hclog.Logger
 }
 
 // NewHashicorpVaultStore is the constructor of HashicorpVaultStore.
@@ -38,9 +71,15 @@ func NewHashicorpVaultStore(ctx context.Context, storage logical.Storage, networ
 		storage: storage,
 		network: network,
 		ctx:     ctx,
+		logger:  hclog.NewNullLogger(),
 	}
 }
 
+// SetLogger sets the logger of the store.
+func (store *HashicorpVaultStore) SetLogger(logger hclog.Logger) {
+	store.logger = logger
+}
+
 // FromInMemoryStore creates the HashicorpVaultStore based on the given in-memory store.
 func FromInMemoryStore(ctx context.Context, inMem *in_memory.InMemStore, storage logical.Storage) (*HashicorpVaultStore, error) {
 	// first delete old data
@@ -60,6 +99,10 @@ func FromInMemoryStore(ctx context.Context, inMem *in_memory.InMemStore, storage
 	if err != nil {
 		return nil, err
 	}
+	err = storage.Delete(ctx, WalletSeedPath)
+	if err != nil {
+		return nil, err
+	}
 	err = storage.Delete(ctx, AccountBase)
 	if err != nil {
 		return nil, err
@@ -67,6 +110,14 @@ func FromInMemoryStore(ctx context.Context, inMem *in_memory.InMemStore, storage
 
 	// Create new store
 	newStore := NewHashicorpVaultStore(ctx, storage, inMem.Network())
+	if err := newStore.EnableEncryption(); err != nil {
+		return nil, err
+	}
+
+	// delete the index of the old accounts
+	if err := newStore.clearAccountIndex(); err != nil {
+		return nil, err
+	}
 
 	// Save wallet
 	wallet, err := inMem.OpenWallet()
@@ -86,6 +137,11 @@ func FromInMemoryStore(ctx context.Context, inMem *in_memory.InMemStore, storage
 		}
 	}
 
+	// The imported data is written in the current layout
+	if err := newStore.SetSchemaVersion(CurrentSchemaVersion); err != nil {
+		return nil, err
+	}
+
 	return newStore, nil
 }
 
@@ -112,15 +168,25 @@ func (store *HashicorpVaultStore) SaveWallet(wallet core.Wallet) error {
 	entry := &logical.StorageEntry{
 		Key:      path,
 		Value:    data,
-		SealWrap: false,
+		SealWrap: true,
 	}
 
-	return store.storage.Put(store.ctx, entry)
+	if err := store.storage.Put(store.ctx, entry); err != nil {
+		return err
+	}
+
+	return store.rotateGeneration()
 }
 
 // OpenWallet returns nil,nil if no wallet was found
 func (store *HashicorpVaultStore) OpenWallet() (core.Wallet, error) {
 	path := WalletDataPath
+	if cached, ok := store.cached(path); ok {
+		if ret := store.bindWallet(cached.(core.Wallet)); ret != nil {
+			return ret, nil
+		}
+	}
+
 	entry, err := store.storage.Get(store.ctx, path)
 	if err != nil {
 		return nil, err
@@ -128,19 +194,80 @@ func (store *HashicorpVaultStore) OpenWallet() (core.Wallet, error) {
 
 	// Return nothing if there is no record
 	if entry == nil {
-		return nil, fmt.Errorf("wallet not found")
+		return nil, ErrWalletNotFound
+	}
+
+	var walletType struct {
+		Type core.WalletType `json:"type"`
+	}
+	if err := json.Unmarshal(entry.Value, &walletType); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal wallet type")
 	}
 
 	// un-marshal
-	ret := &wallet_hd.HDWallet{} // not hardcode HDWallet
-	ret.SetContext(store.freshContext())
-	if err := json.Unmarshal(entry.Value, &ret); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal HD Wallet object")
+	var ret core.Wallet
+	switch walletType.Type {
+	case core.HDWallet, "":
+		ret = &wallet_hd.HDWallet{}
+	case core.ND:
+		ret = &wallet_nd.NDWallet{}
+	default:
+		return nil, fmt.Errorf("unsupported wallet type %s", walletType.Type)
 	}
 
+	if err := json.Unmarshal(entry.Value, ret); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal %s wallet object", walletType.Type)
+	}
+	ret.SetContext(store.freshContext())
+	store.cacheEntry(path, ret)
+
 	return ret, nil
 }
 
+// SaveWalletSeed stores the seed of the HD wallet. The seed is always stored encrypted.
+func (store *HashicorpVaultStore) SaveWalletSeed(seed []byte) error {
+	if !store.canEncrypt() {
+		return fmt.Errorf("wallet seed can't be stored without encryption")
+	}
+
+	data, err := store.encrypt(seed)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt wallet seed")
+	}
+
+	entry := &logical.StorageEntry{
+		Key:      WalletSeedPath,
+		Value:    data,
+		SealWrap: true,
+	}
+	return store.storage.Put(store.ctx, entry)
+}
+
+// DeleteWalletSeed deletes the seed of the HD wallet.
+func (store *HashicorpVaultStore) DeleteWalletSeed() error {
+	return store.storage.Delete(store.ctx, WalletSeedPath)
+}
+
+// OpenWalletSeed returns the seed of the HD wallet. Returns nil,nil if the seed is not stored.
+func (store *HashicorpVaultStore) OpenWalletSeed() ([]byte, error) {
+	entry, err := store.storage.Get(store.ctx, WalletSeedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return nothing if there is no record
+	if entry == nil {
+		return nil, nil
+	}
+
+	seed, err := store.decrypt(entry.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt wallet seed")
+	}
+
+	return seed, nil
+}
+
 // ListAccounts returns an empty array for no accounts
 func (store *HashicorpVaultStore) ListAccounts() ([]core.ValidatorAccount, error) {
 	w, err := store.OpenWallet()
@@ -153,25 +280,71 @@ func (store *HashicorpVaultStore) ListAccounts() ([]core.ValidatorAccount, error
 
 // SaveAccount stores the given account in DB.
 func (store *HashicorpVaultStore) SaveAccount(account core.ValidatorAccount) error {
+	accountType := AccountType(account)
+	if len(accountType) == 0 {
+		return fmt.Errorf("unsupported account type %T", account)
+	}
+
 	// data
-	data, err := json.Marshal(account)
+	accountData, err := json.Marshal(account)
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal account object")
 	}
 
+	data, err := json.Marshal(&accountRecord{
+		Type:    accountType,
+		Account: accountData,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal account record")
+	}
+
+	// encrypt account secrets
+	data, err = store.encrypt(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt account object")
+	}
+
 	// put wallet data
 	path := fmt.Sprintf(AccountPath, account.ID().String())
+	existing, err := store.storage.Get(store.ctx, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get record with path '%s'", path)
+	}
+
 	entry := &logical.StorageEntry{
 		Key:      path,
 		Value:    data,
-		SealWrap: false,
+		SealWrap: true,
 	}
-	return store.storage.Put(store.ctx, entry)
+	if err := store.storage.Put(store.ctx, entry); err != nil {
+		return err
+	}
+
+	publicKey := hex.EncodeToString(account.ValidatorPublicKey().Marshal())
+	if err := store.indexAccount(publicKey, account.ID()); err != nil {
+		return errors.Wrap(err, "failed to index account")
+	}
+
+	if existing == nil {
+		if err := store.recordImport(publicKey); err != nil {
+			return errors.Wrap(err, "failed to record account import time")
+		}
+	}
+	store.logger.Debug("Saved account", "account_id", account.ID().String(), "public_key", publicKey, "type", accountType, "new", existing == nil)
+
+	return store.rotateGeneration()
 }
 
 // OpenAccount opens an account by the given ID. Returns nil,nil if no account was found.
 func (store *HashicorpVaultStore) OpenAccount(accountID uuid.UUID) (core.ValidatorAccount, error) {
 	path := fmt.Sprintf(AccountPath, accountID)
+	if cached, ok := store.cached(path); ok {
+		if ret := store.bindAccount(cached.(core.ValidatorAccount)); ret != nil {
+			return ret, nil
+		}
+	}
+
 	entry, err := store.storage.Get(store.ctx, path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get record with path '%s'", path)
@@ -182,22 +355,74 @@ func (store *HashicorpVaultStore) OpenAccount(accountID uuid.UUID) (core.Validat
 		return nil, nil
 	}
 
+	// decrypt account secrets
+	data, err := store.decrypt(entry.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt account object")
+	}
+
+	var record accountRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal account record")
+	}
+
+	if record.Account == nil {
+		record.Type = HDAccountType
+		record.Account = data
+	}
+
 	// un-marshal
-	ret := &wallet_hd.HDAccount{} // not hardcode HDAccount
-	ret.SetContext(store.freshContext())
-	if err := json.Unmarshal(entry.Value, &ret); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal HD account object")
+	var ret core.ValidatorAccount
+	switch record.Type {
+	case HDAccountType:
+		ret = &wallet_hd.HDAccount{}
+	case NDAccountType:
+		ret = &wallet_nd.NDAccount{}
+	default:
+		return nil, fmt.Errorf("unsupported account type %s", record.Type)
 	}
+
+	if err := json.Unmarshal(record.Account, ret); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal %s account object", record.Type)
+	}
+	ret.SetContext(store.freshContext())
+	store.cacheEntry(path, ret)
+
 	return ret, nil
 }
 
 // DeleteAccount deletes the given account
 func (store *HashicorpVaultStore) DeleteAccount(accountID uuid.UUID) error {
+	account, err := store.OpenAccount(accountID)
+	if err != nil {
+		return errors.Wrap(err, "failed to open account")
+	}
+
 	path := fmt.Sprintf(AccountPath, accountID)
 	if err := store.storage.Delete(store.ctx, path); err != nil {
 		return errors.Wrapf(err, "failed to delete record with path '%s'", path)
 	}
-	return nil
+
+	if account != nil {
+		if err := store.unindexAccount(account); err != nil {
+			return errors.Wrap(err, "failed to delete account index entry")
+		}
+	}
+	store.logger.Info("Deleted account", "account_id", accountID.String())
+
+	return store.rotateGeneration()
+}
+
+// AccountType returns the type of the given account. Returns an empty string for unsupported accounts.
+func AccountType(account core.ValidatorAccount) string {
+	switch account.(type) {
+	case *wallet_hd.HDAccount:
+		return HDAccountType
+	case *wallet_nd.NDAccount:
+		return NDAccountType
+	default:
+		return ""
+	}
 }
 
 // SetEncryptor sets the given encryptor. Could be nil value.
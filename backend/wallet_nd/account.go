@@ -0,0 +1,157 @@
+package wallet_nd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/eth1_deposit"
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// NDAccount is an individually imported validator account.
+// It has no base path and the withdrawal public key is optional.
+type NDAccount struct {
+	id               uuid.UUID
+	name             string
+	validationKey    *core.HDKey
+	withdrawalPubKey e2types.PublicKey
+	context          *core.WalletContext
+}
+
+// NewImportedAccount is the constructor of NDAccount. withdrawalPubKey could be nil.
+// The name defaults to "imported-" followed by the first bytes of the validation public key.
+func NewImportedAccount(name string, validationKey []byte, withdrawalPubKey e2types.PublicKey, context *core.WalletContext) (*NDAccount, error) {
+	key, err := core.NewHDKeyFromPrivateKey(validationKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(name) == 0 {
+		name = "imported-" + hex.EncodeToString(key.PublicKey().Marshal()[:4])
+	}
+
+	return &NDAccount{
+		id:               uuid.New(),
+		name:             name,
+		validationKey:    key,
+		withdrawalPubKey: withdrawalPubKey,
+		context:          context,
+	}, nil
+}
+
+// ID provides the ID for the account.
+func (account *NDAccount) ID() uuid.UUID {
+	return account.id
+}
+
+// Name provides the name for the account.
+func (account *NDAccount) Name() string {
+	return account.name
+}
+
+// BasePath is always empty since the account was not derived.
+func (account *NDAccount) BasePath() string {
+	return ""
+}
+
+// ValidatorPublicKey provides the public key for the validation key.
+func (account *NDAccount) ValidatorPublicKey() e2types.PublicKey {
+	return account.validationKey.PublicKey()
+}
+
+// WithdrawalPublicKey provides the public key for the withdrawal key. Returns nil if unknown.
+func (account *NDAccount) WithdrawalPublicKey() e2types.PublicKey {
+	return account.withdrawalPubKey
+}
+
+// ValidationKeySign signs data with the validation key.
+func (account *NDAccount) ValidationKeySign(data []byte) (e2types.Signature, error) {
+	return account.validationKey.Sign(data)
+}
+
+// GetDepositData returns the deposit data of the account.
+func (account *NDAccount) GetDepositData() (map[string]interface{}, error) {
+	if account.withdrawalPubKey == nil {
+		return nil, fmt.Errorf("account has no withdrawal key")
+	}
+
+	// The key manager library only knows the fork version of its own networks and panics on others
+	network := account.context.Storage.Network()
+	switch network {
+	case core.TestNetwork, core.LaunchTestNetwork, core.MainNetwork:
+	default:
+		return nil, fmt.Errorf("deposit data of network %s is only generated by the deposit data endpoint", network)
+	}
+
+	depositData, root, err := eth1_deposit.DepositData(
+		account.validationKey,
+		account.withdrawalPubKey.Marshal(),
+		network,
+		eth1_deposit.MaxEffectiveBalanceInGwei,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"amount":                depositData.GetAmount(),
+		"publicKey":             hex.EncodeToString(depositData.GetPublicKey()),
+		"signature":             hex.EncodeToString(depositData.GetSignature()),
+		"withdrawalCredentials": hex.EncodeToString(depositData.GetWithdrawalCredentials()),
+		"depositDataRoot":       hex.EncodeToString(root[:]),
+	}, nil
+}
+
+// SetContext sets the wallet context.
+func (account *NDAccount) SetContext(ctx *core.WalletContext) {
+	account.context = ctx
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (account *NDAccount) MarshalJSON() ([]byte, error) {
+	data := map[string]interface{}{
+		"id":            account.id,
+		"name":          account.name,
+		"validationKey": account.validationKey,
+	}
+	if account.withdrawalPubKey != nil {
+		data["withdrawalPubKey"] = hex.EncodeToString(account.withdrawalPubKey.Marshal())
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (account *NDAccount) UnmarshalJSON(data []byte) error {
+	var v struct {
+		ID               uuid.UUID   `json:"id"`
+		Name             string      `json:"name"`
+		ValidationKey    *core.HDKey `json:"validationKey"`
+		WithdrawalPubKey string      `json:"withdrawalPubKey"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.ValidationKey == nil {
+		return fmt.Errorf("could not find var: validationKey")
+	}
+
+	account.id = v.ID
+	account.name = v.Name
+	account.validationKey = v.ValidationKey
+	account.withdrawalPubKey = nil
+
+	if len(v.WithdrawalPubKey) > 0 {
+		byts, err := hex.DecodeString(v.WithdrawalPubKey)
+		if err != nil {
+			return err
+		}
+		if account.withdrawalPubKey, err = e2types.BLSPublicKeyFromBytes(byts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package wallet_nd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/bloxapp/eth2-key-manager/core"
+	"github.com/bloxapp/eth2-key-manager/wallet_hd"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// NDWallet is a non-deterministic wallet. It holds individually imported accounts
+// next to accounts derived from the wallet seed.
+type NDWallet struct {
+	id          uuid.UUID
+	walletType  core.WalletType
+	indexMapper map[string]uuid.UUID
+	context     *core.WalletContext
+}
+
+// NewNDWallet is the constructor of NDWallet.
+func NewNDWallet(context *core.WalletContext) *NDWallet {
+	return &NDWallet{
+		id:          uuid.New(),
+		walletType:  core.ND,
+		indexMapper: make(map[string]uuid.UUID),
+		context:     context,
+	}
+}
+
+// FromHDWallet converts the given HD wallet to a non-deterministic wallet with the same ID and accounts.
+func FromHDWallet(wallet *wallet_hd.HDWallet, context *core.WalletContext) (*NDWallet, error) {
+	data, err := json.Marshal(wallet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal HD wallet")
+	}
+
+	ret := &NDWallet{}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal HD wallet")
+	}
+	ret.walletType = core.ND
+	ret.context = context
+
+	return ret, nil
+}
+
+// ID provides the ID for the wallet.
+func (wallet *NDWallet) ID() uuid.UUID {
+	return wallet.id
+}
+
+// Type provides the type of the wallet.
+func (wallet *NDWallet) Type() core.WalletType {
+	return wallet.walletType
+}
+
+// GetNextAccountIndex provides next index to derive an account at.
+// Imported accounts are not counted.
+func (wallet *NDWallet) GetNextAccountIndex() int {
+	next := 0
+	for _, account := range wallet.Accounts() {
+		if len(account.BasePath()) == 0 {
+			continue
+		}
+
+		index, err := strconv.Atoi(account.BasePath()[1:])
+		if err == nil && index >= next {
+			next = index + 1
+		}
+	}
+	return next
+}
+
+// CreateValidatorAccount derives a new validator account from the given seed.
+func (wallet *NDWallet) CreateValidatorAccount(seed []byte, indexPointer *int) (core.ValidatorAccount, error) {
+	// Resolve index to create account at
+	var index int
+	if indexPointer != nil {
+		index = *indexPointer
+	} else {
+		index = wallet.GetNextAccountIndex()
+	}
+
+	// Create the master key based on the seed and network.
+	key, err := core.MasterKeyFromSeed(seed, wallet.context.Storage.Network())
+	if err != nil {
+		return nil, err
+	}
+
+	validatorKey, err := key.Derive(fmt.Sprintf(wallet_hd.ValidatorKeyPath, index))
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawalKey, err := key.Derive(fmt.Sprintf(wallet_hd.WithdrawalKeyPath, index))
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := wallet_hd.NewValidatorAccount(
+		fmt.Sprintf("account-%d", index),
+		validatorKey,
+		withdrawalKey.PublicKey(),
+		fmt.Sprintf(wallet_hd.BaseAccountPath, index),
+		wallet.context,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := wallet.AddValidatorAccount(account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// AddValidatorAccount adds the given account to the wallet and stores both.
+func (wallet *NDWallet) AddValidatorAccount(account core.ValidatorAccount) error {
+	validatorPublicKey := hex.EncodeToString(account.ValidatorPublicKey().Marshal())
+	if _, exists := wallet.indexMapper[validatorPublicKey]; exists {
+		return fmt.Errorf("account %s already exists", validatorPublicKey)
+	}
+
+	// Register new account and save wallet
+	reset := func() {
+		delete(wallet.indexMapper, validatorPublicKey)
+	}
+	wallet.indexMapper[validatorPublicKey] = account.ID()
+
+	if err := wallet.context.Storage.SaveAccount(account); err != nil {
+		reset()
+		return err
+	}
+
+	if err := wallet.context.Storage.SaveWallet(wallet); err != nil {
+		reset()
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAccountByPublicKey deletes an account from the wallet given its public key.
+func (wallet *NDWallet) DeleteAccountByPublicKey(pubKey string) error {
+	account, err := wallet.AccountByPublicKey(pubKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to get account by public key")
+	}
+
+	if err := wallet.context.Storage.DeleteAccount(account.ID()); err != nil {
+		return errors.Wrap(err, "failed to delete account from store")
+	}
+
+	delete(wallet.indexMapper, pubKey)
+	if err := wallet.context.Storage.SaveWallet(wallet); err != nil {
+		return errors.Wrap(err, "failed to save wallet")
+	}
+	return nil
+}
+
+// Accounts provides all accounts in the wallet sorted by name.
+func (wallet *NDWallet) Accounts() []core.ValidatorAccount {
+	accounts := make([]core.ValidatorAccount, 0)
+	for _, id := range wallet.indexMapper {
+		account, err := wallet.AccountByID(id)
+		if err != nil || account == nil {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name() < accounts[j].Name()
+	})
+	return accounts
+}
+
+// AccountByID provides a single account from the wallet given its ID.
+func (wallet *NDWallet) AccountByID(id uuid.UUID) (core.ValidatorAccount, error) {
+	ret, err := wallet.context.Storage.OpenAccount(id)
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, nil
+	}
+	ret.SetContext(wallet.context)
+	return ret, nil
+}
+
+// AccountByPublicKey provides a single account from the wallet given its public key.
+func (wallet *NDWallet) AccountByPublicKey(pubKey string) (core.ValidatorAccount, error) {
+	id, exists := wallet.indexMapper[pubKey]
+	if !exists {
+		return nil, wallet_hd.ErrAccountNotFound
+	}
+	return wallet.AccountByID(id)
+}
+
+// SetContext sets the wallet context.
+func (wallet *NDWallet) SetContext(ctx *core.WalletContext) {
+	wallet.context = ctx
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (wallet *NDWallet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":          wallet.id,
+		"type":        wallet.walletType,
+		"indexMapper": wallet.indexMapper,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (wallet *NDWallet) UnmarshalJSON(data []byte) error {
+	var v struct {
+		ID          uuid.UUID            `json:"id"`
+		Type        core.WalletType      `json:"type"`
+		IndexMapper map[string]uuid.UUID `json:"indexMapper"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.IndexMapper == nil {
+		v.IndexMapper = make(map[string]uuid.UUID)
+	}
+
+	wallet.id = v.ID
+	wallet.walletType = v.Type
+	wallet.indexMapper = v.IndexMapper
+	return nil
+}
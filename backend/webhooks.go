@@ -0,0 +1,248 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+// Webhook event types
+const (
+	// WebhookEventSlashable is sent when a message is refused by slashing protection
+	WebhookEventSlashable = "slashable_refused"
+
+	// WebhookEventAccountPaused is sent when an account is paused
+	WebhookEventAccountPaused = "account_paused"
+
+	// WebhookEventHalted is sent when every account of the mount is paused
+	WebhookEventHalted = "mount_halted"
+
+	// WebhookEventIntegrityFailure is sent when stored or restored history fails an integrity check
+	WebhookEventIntegrityFailure = "integrity_failure"
+)
+
+// Webhook request headers
+const (
+	// WebhookEventHeader holds the event type
+	WebhookEventHeader = "X-Key-Vault-Event"
+
+	// WebhookSignatureHeader holds the HEX encoded HMAC-SHA256 of the body, prefixed with "sha256="
+	WebhookSignatureHeader = "X-Key-Vault-Signature"
+)
+
+// Webhook delivery settings
+const (
+	webhookQueueSize = 256
+	webhookWorkers   = 2
+	webhookAttempts  = 3
+	webhookTimeout   = 10 * time.Second
+
+	// defaultWebhookRetryWait is the wait before the first retry, doubled on every retry
+	defaultWebhookRetryWait = time.Second
+)
+
+// WebhookEvent is the JSON body sent to the webhook targets.
+type WebhookEvent struct {
+	Type        string `json:"type"`
+	Time        string `json:"time"`
+	Mount       string `json:"mount"`
+	RequestID   string `json:"request_id"`
+	PublicKey   string `json:"public_key,omitempty"`
+	MessageType string `json:"message_type,omitempty"`
+	Slot        uint64 `json:"slot,omitempty"`
+	SourceEpoch uint64 `json:"source_epoch,omitempty"`
+	TargetEpoch uint64 `json:"target_epoch,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// webhookDelivery is an event to send to a target.
+type webhookDelivery struct {
+	target    *Webhook
+	eventType string
+	body      []byte
+}
+
+// webhookDispatcher sends the events in the background so signing isn't delayed by the targets.
+// Deliveries are dropped when the queue is full.
+type webhookDispatcher struct {
+	client    *http.Client
+	retryWait time.Duration
+	queue     chan *webhookDelivery
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+// newWebhookDispatcher is the constructor of webhookDispatcher.
+func newWebhookDispatcher() *webhookDispatcher {
+	return &webhookDispatcher{
+		client: &http.Client{
+			Timeout: webhookTimeout,
+		},
+		retryWait: defaultWebhookRetryWait,
+		queue:     make(chan *webhookDelivery, webhookQueueSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// enqueue schedules the delivery of the given event, the workers are started on first use.
+func (dispatcher *webhookDispatcher) enqueue(logger hclog.Logger, delivery *webhookDelivery) {
+	dispatcher.startOnce.Do(func() {
+		for i := 0; i < webhookWorkers; i++ {
+			dispatcher.wg.Add(1)
+			go dispatcher.work(logger)
+		}
+	})
+
+	select {
+	case dispatcher.queue <- delivery:
+	default:
+		logger.Warn("Webhook queue is full, dropping event", "event", delivery.eventType, "url", delivery.target.URL)
+	}
+}
+
+// stop stops the workers, pending deliveries are dropped.
+func (dispatcher *webhookDispatcher) stop() {
+	dispatcher.stopOnce.Do(func() {
+		close(dispatcher.done)
+	})
+	dispatcher.wg.Wait()
+}
+
+func (dispatcher *webhookDispatcher) work(logger hclog.Logger) {
+	defer dispatcher.wg.Done()
+
+	for {
+		select {
+		case <-dispatcher.done:
+			return
+		case delivery := <-dispatcher.queue:
+			if err := dispatcher.deliver(delivery); err != nil {
+				logger.Error("Failed to deliver webhook event", "event", delivery.eventType, "url", delivery.target.URL, "error", err)
+			}
+		}
+	}
+}
+
+// deliver sends the event, retrying a bounded number of times.
+func (dispatcher *webhookDispatcher) deliver(delivery *webhookDelivery) error {
+	wait := dispatcher.retryWait
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if err = dispatcher.send(delivery); err == nil {
+			return nil
+		}
+
+		if attempt == webhookAttempts {
+			break
+		}
+
+		select {
+		case <-dispatcher.done:
+			return errors.Wrap(err, "stopped before retrying")
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+
+	return errors.Wrapf(err, "giving up after %d attempts", webhookAttempts)
+}
+
+func (dispatcher *webhookDispatcher) send(delivery *webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.target.URL, bytes.NewReader(delivery.body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create HTTP request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.eventType)
+	if len(delivery.target.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(delivery.target.Secret, delivery.body))
+	}
+
+	resp, err := dispatcher.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send HTTP request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// webhookSignature returns the HEX encoded HMAC-SHA256 of the body.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendEvent sends the given event to the webhook targets of the mount.
+// Failing to send doesn't fail the request.
+func (b *backend) sendEvent(ctx context.Context, req *logical.Request, event *WebhookEvent) {
+	logger := b.requestLogger(req)
+
+	targets, err := listWebhooks(ctx, req.Storage)
+	if err != nil {
+		logger.Error("Failed to list webhooks", "error", err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	event.Time = b.clock().UTC().Format(time.RFC3339Nano)
+	event.Mount = req.MountPoint
+	event.RequestID = req.ID
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Failed to marshal webhook event", "error", err)
+		return
+	}
+
+	for _, target := range targets {
+		b.webhooks.enqueue(b.Logger(), &webhookDelivery{
+			target:    target,
+			eventType: event.Type,
+			body:      body,
+		})
+	}
+}
+
+// sendPauseEvents sends the pause of the given account, and the halt of the mount if every account is paused.
+func (b *backend) sendPauseEvents(ctx context.Context, req *logical.Request, storage *store.HashicorpVaultStore, publicKey string) {
+	b.sendEvent(ctx, req, &WebhookEvent{
+		Type:      WebhookEventAccountPaused,
+		PublicKey: publicKey,
+	})
+
+	accounts, paused, err := countPausedAccounts(storage)
+	if err != nil {
+		b.requestLogger(req).Error("Failed to count paused accounts", "error", err)
+		return
+	}
+
+	if accounts > 0 && paused == accounts {
+		b.sendEvent(ctx, req, &WebhookEvent{
+			Type:   WebhookEventHalted,
+			Reason: "all accounts are paused",
+		})
+	}
+}
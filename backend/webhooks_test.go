@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bloxapp/key-vault/backend/store"
+)
+
+type receivedEvent struct {
+	event     WebhookEvent
+	signature string
+	body      []byte
+}
+
+func TestWebhooks(t *testing.T) {
+	lb, _ := getBackend(t)
+	b := lb.(*backend)
+	b.webhooks.retryWait = time.Millisecond
+	defer b.webhooks.stop()
+
+	// The receiver fails the first delivery to test retries
+	var attempts int32
+	events := make(chan *receivedEvent, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		require.NoError(t, err)
+
+		received := &receivedEvent{
+			signature: request.Header.Get(WebhookSignatureHeader),
+			body:      body,
+		}
+		require.NoError(t, json.Unmarshal(body, &received.event))
+		require.Equal(t, received.event.Type, request.Header.Get(WebhookEventHeader))
+		events <- received
+	}))
+	defer receiver.Close()
+
+	next := func(t *testing.T) *receivedEvent {
+		select {
+		case received := <-events:
+			return received
+		case <-time.After(5 * time.Second):
+			t.Fatal("no webhook event received")
+			return nil
+		}
+	}
+
+	req := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+	setupBaseStorage(t, req)
+	require.NoError(t, setupStorageWithWalletAndAccounts(req.Storage))
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		r := logical.TestRequest(t, operation, path)
+		r.Storage = req.Storage
+		r.Data = data
+		res, err := b.HandleRequest(context.Background(), r)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Reject invalid url", func(t *testing.T) {
+		res := request(logical.CreateOperation, "webhooks/alerts", map[string]interface{}{
+			"url": "ftp://example.com",
+		})
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+	})
+
+	t.Run("Configure webhook", func(t *testing.T) {
+		res := request(logical.CreateOperation, "webhooks/alerts", map[string]interface{}{
+			"url":    receiver.URL,
+			"secret": "webhook-secret",
+		})
+		require.Equal(t, receiver.URL, res.Data["url"])
+		require.Equal(t, true, res.Data["has_secret"])
+		require.Nil(t, res.Data["secret"])
+
+		res = request(logical.ListOperation, "webhooks/", nil)
+		require.Equal(t, []string{"alerts"}, res.Data["keys"])
+	})
+
+	t.Run("Slashable message is sent", func(t *testing.T) {
+		request(logical.CreateOperation, "accounts/sign-attestation", basicAttestationData())
+		slashable := basicAttestationData()
+		slashable["beaconBlockRoot"] = "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0"
+		signReq := logical.TestRequest(t, logical.CreateOperation, "accounts/sign-attestation")
+		signReq.Storage = req.Storage
+		signReq.Data = slashable
+		res, err := b.HandleRequest(context.Background(), signReq)
+		require.NoError(t, err)
+		require.EqualValues(t, 412, res.Data["http_status_code"])
+
+		received := next(t)
+		require.Equal(t, WebhookEventSlashable, received.event.Type)
+		require.Equal(t, basicAttestationData()["public_key"], received.event.PublicKey)
+		require.Equal(t, store.AuditMessageAttestation, received.event.MessageType)
+		require.EqualValues(t, 8878, received.event.TargetEpoch)
+		require.Contains(t, received.event.Reason, "slashable")
+		require.Equal(t, "sha256="+webhookSignature("webhook-secret", received.body), received.signature)
+		require.EqualValues(t, 2, atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("Pausing all accounts is sent", func(t *testing.T) {
+		publicKeys, err := store.NewHashicorpVaultStore(context.Background(), req.Storage, "").ListAccountPublicKeys()
+		require.NoError(t, err)
+		for i, publicKey := range publicKeys {
+			request(logical.UpdateOperation, "accounts/"+publicKey+"/metadata", map[string]interface{}{
+				"status": "paused",
+			})
+
+			// Pausing the last account halts the mount, events may arrive in any order
+			expected := []string{WebhookEventAccountPaused}
+			if i == len(publicKeys)-1 {
+				expected = append(expected, WebhookEventHalted)
+			}
+			var types []string
+			for range expected {
+				types = append(types, next(t).event.Type)
+			}
+			require.ElementsMatch(t, expected, types)
+		}
+	})
+
+	t.Run("Invalid backup is sent", func(t *testing.T) {
+		res := request(logical.CreateOperation, "restore", map[string]interface{}{
+			"backup":   hex.EncodeToString([]byte("{}")),
+			"password": "password",
+		})
+		require.EqualValues(t, 400, res.Data["http_status_code"])
+
+		received := next(t)
+		require.Equal(t, WebhookEventIntegrityFailure, received.event.Type)
+		require.True(t, strings.Contains(received.event.Reason, "invalid backup"))
+	})
+
+	t.Run("Deleted webhook receives nothing", func(t *testing.T) {
+		request(logical.DeleteOperation, "webhooks/alerts", nil)
+		res := request(logical.ReadOperation, "webhooks/alerts", nil)
+		require.Nil(t, res)
+	})
+}
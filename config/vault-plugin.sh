@@ -24,5 +24,29 @@ vault secrets enable \
 echo "Configuring Test network..."
 vault write ethereum/launchtest/config network="launchtest"
 
+# Enable mainnet
+echo "Enabling Mainnet..."
+vault secrets enable \
+    -path=ethereum/mainnet \
+    -description="Eth Signing Wallet - Mainnet" \
+    -plugin-name=ethsign plugin > /dev/null 2>&1
+
+echo "Configuring Mainnet..."
+vault write ethereum/mainnet/config network="mainnet"
+
+# Enable prater network
+echo "Enabling Prater network..."
+vault secrets enable \
+    -path=ethereum/prater \
+    -description="Eth Signing Wallet - Prater Network" \
+    -plugin-name=ethsign plugin > /dev/null 2>&1
+
+echo "Configuring Prater network..."
+vault write ethereum/prater/config network="prater"
+
+# Custom networks are enabled the same way and configured with their genesis, e.g.
+# vault write ethereum/devnet/config network="custom" genesis_fork_version="<hex>" \
+#     genesis_validators_root="<hex>" genesis_time=<unix time> fork_schedule="<epoch>:<hex>,..."
+
 # Reload plugin
 curl --header "X-Vault-Token: $(cat /data/keys/vault.root.token)" --request PUT --data '{"plugin": "ethsign"}'  http://127.0.0.1:8200/v1/sys/plugins/reload/backend
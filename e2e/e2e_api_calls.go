@@ -53,6 +53,12 @@ func (e *ServiceError) DataValue(field string) interface{} {
 	return e.Data["data"].(map[string]interface{})[field]
 }
 
+// Code returns the error code from the data
+func (e *ServiceError) Code() string {
+	code, _ := e.DataValue("code").(string)
+	return code
+}
+
 func init() {
 	var err error
 	imageName := "key-vault:" + uuid.New()
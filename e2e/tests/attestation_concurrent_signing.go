@@ -2,7 +2,6 @@ package tests
 
 import (
 	"encoding/hex"
-	"fmt"
 	"math/rand"
 	"strconv"
 	"testing"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/bloxapp/key-vault/e2e"
 	"github.com/bloxapp/key-vault/e2e/shared"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // AttestationConcurrentSigning tests signing method concurrently.
@@ -84,8 +84,7 @@ func runSlashableAttestation(t *testing.T, setup *e2e.BaseSetup, pubKey string)
 	require.Error(t, err, "did not slash")
 	require.IsType(t, &e2e.ServiceError{}, err)
 
-	errValue := err.(*e2e.ServiceError).ErrorValue()
-	protected := errValue == fmt.Sprintf("1 error occurred:\n\t* failed to sign attestation: slashable attestation (DoubleVote), not signing\n\n") ||
-		errValue == fmt.Sprintf("1 error occurred:\n\t* locked\n\n")
+	code := err.(*e2e.ServiceError).Code()
+	protected := code == string(errorex.CodeSlashableDoubleVote) || code == string(errorex.CodeLocked)
 	require.True(t, protected, err.Error())
 }
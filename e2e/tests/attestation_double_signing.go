@@ -2,13 +2,14 @@ package tests
 
 import (
 	"encoding/hex"
-	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/bloxapp/key-vault/e2e"
 	"github.com/bloxapp/key-vault/e2e/shared"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // AttestationDoubleSigning tests double signing case
@@ -60,8 +61,9 @@ func (test *AttestationDoubleSigning) Run(t *testing.T) {
 			"targetRoot":      "17959acc370274756fa5e9fdd7e7adf17204f49cc8457e49438c42c4883cbfb0",
 		},
 	)
-	expectedErr := fmt.Sprintf("1 error occurred:\n\t* failed to sign attestation: slashable attestation (DoubleVote), not signing\n\n")
 	require.Error(t, err)
 	require.IsType(t, &e2e.ServiceError{}, err)
-	require.EqualValues(t, expectedErr, err.(*e2e.ServiceError).ErrorValue())
+	require.EqualValues(t, errorex.CodeSlashableDoubleVote, err.(*e2e.ServiceError).Code())
+	require.EqualValues(t, http.StatusPreconditionFailed, err.(*e2e.ServiceError).DataValue("status_code"))
+	require.EqualValues(t, "failed to sign attestation: slashable attestation (DoubleVote), not signing", err.(*e2e.ServiceError).DataValue("message"))
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/bloxapp/key-vault/e2e"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // AttestationSigningAccountNotFound tests sign attestation when account not found
@@ -44,4 +45,5 @@ func (test *AttestationSigningAccountNotFound) Run(t *testing.T) {
 	require.IsType(t, &e2e.ServiceError{}, err)
 	require.EqualValues(t, "account not found", err.(*e2e.ServiceError).DataValue("message"))
 	require.EqualValues(t, http.StatusNotFound, err.(*e2e.ServiceError).DataValue("status_code"))
+	require.EqualValues(t, errorex.CodeAccountNotFound, err.(*e2e.ServiceError).Code())
 }
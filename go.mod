@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/Azure/go-ansiterm v0.0.0-20170929234023-d6e3b3328b78 // indirect
 	github.com/Microsoft/hcsshim v0.8.9 // indirect
+	github.com/armon/go-metrics v0.3.3
 	github.com/bloxapp/eth2-key-manager v0.2.10
 	github.com/containerd/containerd v1.4.0 // indirect
 	github.com/containerd/continuity v0.0.0-20200710164510-efbc4488d8fe // indirect
@@ -23,7 +24,10 @@ require (
 	github.com/opencontainers/runc v0.1.1 // indirect
 	github.com/pborman/uuid v1.2.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.7.1
+	github.com/prometheus/common v0.10.0
 	github.com/prysmaticlabs/ethereumapis v0.0.0-20200827165051-58ccb36e36b9
+	github.com/prysmaticlabs/go-ssz v0.0.0-20200612203617-6d5c9aa213ae
 	github.com/prysmaticlabs/prysm v1.0.0-alpha.25
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1
@@ -0,0 +1,333 @@
+package keymanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Auth methods
+const (
+	// AuthMethodAppRole logs in with a role id and a secret id
+	AuthMethodAppRole = "approle"
+
+	// AuthMethodKubernetes logs in with the service account JWT of the pod
+	AuthMethodKubernetes = "kubernetes"
+
+	// AuthMethodTokenFile reads the token from a file kept up to date by another process, such as Vault Agent
+	AuthMethodTokenFile = "token_file"
+)
+
+// Auth settings
+const (
+	// authBasePath is the base path of the Vault auth endpoints
+	authBasePath = "/v1/auth"
+
+	// defaultKubernetesJWTFile is where Kubernetes mounts the service account token
+	defaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// AuthConfig configures how the key manager gets its Vault token.
+type AuthConfig struct {
+	// Method is approle, kubernetes or token_file
+	Method string `json:"method"`
+
+	// Mount is the path the auth method is enabled at, the method name by default
+	Mount string `json:"mount"`
+
+	// RoleID and SecretID are the AppRole credentials
+	RoleID   string `json:"role_id"`
+	SecretID string `json:"secret_id"`
+
+	// Role is the Kubernetes auth role
+	Role string `json:"role"`
+
+	// JWTFile holds the Kubernetes service account JWT, read on every login
+	JWTFile string `json:"jwt_file"`
+
+	// TokenFile holds the token of the token_file method, read again when Vault denies it
+	TokenFile string `json:"token_file"`
+}
+
+// validate returns an error if required settings of the auth method are missing.
+func (c *AuthConfig) validate() error {
+	switch c.Method {
+	case AuthMethodAppRole:
+		if len(c.RoleID) == 0 || len(c.SecretID) == 0 {
+			return NewGenericErrorMessage("approle auth requires role_id and secret_id")
+		}
+	case AuthMethodKubernetes:
+		if len(c.Role) == 0 {
+			return NewGenericErrorMessage("kubernetes auth requires role")
+		}
+	case AuthMethodTokenFile:
+		if len(c.TokenFile) == 0 {
+			return NewGenericErrorMessage("token_file auth requires token_file")
+		}
+	default:
+		return NewGenericErrorMessage("unsupported auth method '%s'", c.Method)
+	}
+
+	return nil
+}
+
+// vaultAuthResponse is the response model of the Vault login and renew endpoints.
+type vaultAuthResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// authenticator provides the Vault token of the key manager.
+// Tokens with a lease are renewed in the background once two thirds of the lease have passed,
+// so signing goes on with the current token meanwhile. Expired or denied tokens are replaced by a new login,
+// a single one at a time whoever needs it.
+type authenticator struct {
+	config      *AuthConfig
+	staticToken string
+	address     string
+	client      *http.Client
+	log         *logrus.Entry
+	now         func() time.Time
+
+	lock      sync.Mutex
+	token     string
+	renewable bool
+	renewAt   time.Time
+	expiresAt time.Time
+	renewing  bool
+	login     *loginFlight
+}
+
+// loginFlight is a login in progress, its result is set before done is closed.
+type loginFlight struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// newAuthenticator is the constructor of authenticator, the static token is used if the config is nil.
+func newAuthenticator(log *logrus.Entry, address string, client *http.Client, config *AuthConfig, staticToken string) *authenticator {
+	return &authenticator{
+		config:      config,
+		staticToken: staticToken,
+		address:     address,
+		client:      client,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+// Token returns the token to send, logging in if there is none or it has expired.
+func (a *authenticator) Token() (string, error) {
+	if a.config == nil {
+		return a.staticToken, nil
+	}
+
+	a.lock.Lock()
+	now := a.now()
+	if len(a.token) == 0 || (!a.expiresAt.IsZero() && !now.Before(a.expiresAt)) {
+		a.lock.Unlock()
+		return a.relogin()
+	}
+
+	if !a.renewAt.IsZero() && !now.Before(a.renewAt) && !a.renewing {
+		a.renewing = true
+		go a.renew(a.token, a.renewable)
+	}
+
+	token := a.token
+	a.lock.Unlock()
+	return token, nil
+}
+
+// Invalidate drops the given token after Vault denied it, the next call to Token logs in again.
+// It returns false if the token is static and can't be replaced.
+func (a *authenticator) Invalidate(token string) bool {
+	if a.config == nil {
+		return false
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.token == token {
+		a.token = ""
+	}
+	return true
+}
+
+// relogin gets a new token, waiting for the login in progress if there is one.
+func (a *authenticator) relogin() (string, error) {
+	a.lock.Lock()
+	if flight := a.login; flight != nil {
+		a.lock.Unlock()
+		<-flight.done
+		return flight.token, flight.err
+	}
+	flight := &loginFlight{done: make(chan struct{})}
+	a.login = flight
+	a.lock.Unlock()
+
+	resp, err := a.authenticate()
+
+	a.lock.Lock()
+	a.login = nil
+	if err == nil {
+		a.set(resp)
+		flight.token = a.token
+		a.log.WithField("method", a.config.Method).WithField("lease_duration", resp.Auth.LeaseDuration).Info("logged in to remote vault")
+	}
+	flight.err = err
+	a.lock.Unlock()
+
+	close(flight.done)
+	return flight.token, flight.err
+}
+
+// authenticate logs in with the auth method, the lock must not be held.
+func (a *authenticator) authenticate() (*vaultAuthResponse, error) {
+	var resp vaultAuthResponse
+	switch a.config.Method {
+	case AuthMethodAppRole:
+		if err := a.send(a.loginPath(), "", map[string]string{
+			"role_id":   a.config.RoleID,
+			"secret_id": a.config.SecretID,
+		}, &resp); err != nil {
+			return nil, NewGenericError(err, "failed to log in with approle")
+		}
+	case AuthMethodKubernetes:
+		jwtFile := a.config.JWTFile
+		if len(jwtFile) == 0 {
+			jwtFile = defaultKubernetesJWTFile
+		}
+		jwt, err := ioutil.ReadFile(jwtFile)
+		if err != nil {
+			return nil, NewGenericError(err, "failed to read service account token")
+		}
+
+		if err := a.send(a.loginPath(), "", map[string]string{
+			"role": a.config.Role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}, &resp); err != nil {
+			return nil, NewGenericError(err, "failed to log in with kubernetes")
+		}
+	case AuthMethodTokenFile:
+		token, err := ioutil.ReadFile(a.config.TokenFile)
+		if err != nil {
+			return nil, NewGenericError(err, "failed to read token file")
+		}
+		resp.Auth.ClientToken = strings.TrimSpace(string(token))
+	}
+
+	if len(resp.Auth.ClientToken) == 0 {
+		return nil, NewGenericErrorMessage("no token returned by %s auth", a.config.Method)
+	}
+
+	return &resp, nil
+}
+
+// renew extends the lease of the given token, logging in again if it can't be renewed.
+// The token is served until it expires or the new one is there.
+func (a *authenticator) renew(token string, renewable bool) {
+	var resp vaultAuthResponse
+	var err error
+	if renewable {
+		err = a.send(authBasePath+"/token/renew-self", token, map[string]string{}, &resp)
+	}
+
+	a.lock.Lock()
+	a.renewing = false
+
+	// The token was replaced meanwhile
+	if a.token != token {
+		a.lock.Unlock()
+		return
+	}
+
+	if err == nil && len(resp.Auth.ClientToken) > 0 {
+		a.set(&resp)
+		a.lock.Unlock()
+		a.log.WithField("lease_duration", resp.Auth.LeaseDuration).Debug("renewed remote vault token")
+		return
+	}
+	a.lock.Unlock()
+
+	if err != nil {
+		a.log.WithError(err).Warn("failed to renew remote vault token, logging in again")
+	}
+	if _, err := a.relogin(); err != nil {
+		a.log.WithError(err).Error("failed to log in to remote vault")
+	}
+}
+
+// set keeps the given token and schedules its renewal, the lock must be held.
+func (a *authenticator) set(resp *vaultAuthResponse) {
+	now := a.now()
+	a.token = resp.Auth.ClientToken
+	a.renewable = resp.Auth.Renewable
+	a.renewAt = time.Time{}
+	a.expiresAt = time.Time{}
+
+	if resp.Auth.LeaseDuration > 0 {
+		lease := time.Duration(resp.Auth.LeaseDuration) * time.Second
+		a.renewAt = now.Add(lease * 2 / 3)
+		a.expiresAt = now.Add(lease)
+	}
+}
+
+// loginPath returns the login endpoint of the auth method.
+func (a *authenticator) loginPath() string {
+	mount := a.config.Mount
+	if len(mount) == 0 {
+		mount = a.config.Method
+	}
+	return authBasePath + "/" + strings.Trim(mount, "/") + "/login"
+}
+
+// send posts the given body to the Vault auth endpoint.
+func (a *authenticator) send(path, token string, body interface{}, respBody interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return NewGenericError(err, "failed to marshal request body")
+	}
+
+	endpoint := a.address + path
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return NewGenericError(err, "failed to create HTTP request")
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return NewGenericError(err, "failed to send HTTP request")
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return NewGenericError(err, "failed to read response body")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return NewHTTPRequestError(endpoint, resp.StatusCode, responseBody, "unexpected status code")
+	}
+
+	if err := json.Unmarshal(responseBody, respBody); err != nil {
+		return NewGenericError(err, "failed to decode response body")
+	}
+
+	return nil
+}
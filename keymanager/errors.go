@@ -6,6 +6,8 @@ import (
 	"log"
 
 	"github.com/pkg/errors"
+
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 // HTTPRequestError represents an HTTP request error.
@@ -44,6 +46,46 @@ func (e *HTTPRequestError) String() string {
 	return string(data)
 }
 
+// ServiceError represents an error returned by the remote vault with a stable code.
+type ServiceError struct {
+	Code       errorex.ErrorCode `json:"code"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Message    string            `json:"message,omitempty"`
+}
+
+// NewServiceError is the constructor of ServiceError.
+func NewServiceError(code errorex.ErrorCode, statusCode int, message string) *ServiceError {
+	return &ServiceError{
+		Code:       code,
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
+
+// Error implements error interface.
+func (e *ServiceError) Error() string {
+	return e.String()
+}
+
+// Is returns true if the target is a ServiceError with the same code, so errors.Is matches the predefined errors.
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	return ok && t.Code == e.Code
+}
+
+// String implements fmt.Stringer interface.
+func (e *ServiceError) String() string {
+	if e == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(data)
+}
+
 // GenericError represents the generic error of keymanager.
 type GenericError struct {
 	ErrorMsg string `json:"error"`
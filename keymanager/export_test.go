@@ -0,0 +1,10 @@
+package keymanager
+
+import (
+	"time"
+)
+
+// SetClock sets the clock the Vault token leases of the key manager are checked with.
+func SetClock(km *KeyManager, now func() time.Time) {
+	km.auth.now = now
+}
@@ -5,17 +5,22 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	ethpb "github.com/prysmaticlabs/ethereumapis/eth/v1alpha1"
 	"github.com/prysmaticlabs/prysm/shared/bls"
-	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/event"
 	v1keymanager "github.com/prysmaticlabs/prysm/validator/keymanager/v1"
 	"github.com/sirupsen/logrus"
 
 	"github.com/bloxapp/key-vault/backend"
 	"github.com/bloxapp/key-vault/utils/endpoint"
+	"github.com/bloxapp/key-vault/utils/errorex"
 	"github.com/bloxapp/key-vault/utils/httpex"
 )
 
@@ -30,50 +35,132 @@ var (
 	ErrNoSuchKey          = NewGenericErrorWithMessage("no such key")
 )
 
+// Errors returned by the remote vault, compare with errors.Is
+var (
+	ErrSlashableDoubleVote = &ServiceError{Code: errorex.CodeSlashableDoubleVote}
+	ErrSlashableSurround   = &ServiceError{Code: errorex.CodeSlashableSurround}
+	ErrDoubleProposal      = &ServiceError{Code: errorex.CodeDoubleProposal}
+	ErrLocked              = &ServiceError{Code: errorex.CodeLocked}
+	ErrAccountNotFound     = &ServiceError{Code: errorex.CodeAccountNotFound}
+	ErrNotConfigured       = &ServiceError{Code: errorex.CodeNotConfigured}
+	ErrBadRequest          = &ServiceError{Code: errorex.CodeBadRequest}
+	ErrHalted              = &ServiceError{Code: errorex.CodeHalted}
+)
+
 // KeyManager is a key manager that accesses a remote vault wallet daemon through HTTP connection.
 type KeyManager struct {
 	remoteAddress string
-	accessToken   string
-	originPubKey  string
-	pubKey        [48]byte
+	auth          *authenticator
 	network       string
 	httpClient    *http.Client
+	probeClient   *http.Client
+
+	// originPubKey is the account the metadata is fetched for, empty if the accounts are discovered
+	originPubKey string
+
+	// keys are the accounts the key manager signs with, nil until the accounts are discovered
+	keys            keySet
+	keysLock        sync.RWMutex
+	keysFeed        *event.Feed
+	discover        bool
+	refreshInterval time.Duration
+
+	// stopWatch stops refreshing the discovered accounts, nil if the public keys are configured
+	stopWatch context.CancelFunc
 
 	log *logrus.Entry
 }
 
 // NewKeyManager is the constructor of KeyManager.
+// Discovered accounts are refreshed in the background until Close is called. Validator clients
+// of the v1 key manager never call it, so the refresh runs for the life of the process there.
 func NewKeyManager(log *logrus.Entry, opts *Config) (*KeyManager, error) {
+	return NewKeyManagerWithContext(context.Background(), log, opts)
+}
+
+// NewKeyManagerWithContext is the constructor of KeyManager.
+// Discovered accounts are refreshed in the background until the given context is done or Close is called.
+func NewKeyManagerWithContext(ctx context.Context, log *logrus.Entry, opts *Config) (*KeyManager, error) {
 	if len(opts.Location) == 0 {
 		return nil, NewGenericErrorMessage("wallet location is required")
 	}
-	if len(opts.AccessToken) == 0 {
+	if opts.Auth != nil {
+		if err := opts.Auth.validate(); err != nil {
+			return nil, err
+		}
+	} else if len(opts.AccessToken) == 0 {
 		return nil, NewGenericErrorMessage("wallet access token is required")
 	}
-	if len(opts.PubKey) == 0 {
-		return nil, NewGenericErrorMessage("wallet public key is required")
+
+	refreshInterval := defaultRefreshInterval
+	if len(opts.RefreshInterval) > 0 {
+		interval, err := time.ParseDuration(opts.RefreshInterval)
+		if err != nil || interval <= 0 {
+			return nil, NewGenericErrorMessage("invalid refresh interval '%s'", opts.RefreshInterval)
+		}
+		refreshInterval = interval
 	}
 
-	// Decode public key
-	decodedPubKey, err := hex.DecodeString(opts.PubKey)
+	var dialTLS httpex.DialTLSFunc
+	if opts.TLS != nil {
+		loader, err := newTLSLoader(log, opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		dialTLS = loader.DialTLS
+	}
+
+	httpClient := httpex.CreateClient(dialTLS)
+	km := &KeyManager{
+		remoteAddress:   opts.Location,
+		auth:            newAuthenticator(log, opts.Location, httpClient, opts.Auth, opts.AccessToken),
+		network:         opts.Network,
+		httpClient:      httpClient,
+		probeClient:     httpex.CreateProbeClient(dialTLS),
+		keysFeed:        new(event.Feed),
+		refreshInterval: refreshInterval,
+		log:             log,
+	}
+
+	// The accounts are discovered if no public key is configured
+	var publicKeys []string
+	if len(opts.PubKey) > 0 {
+		publicKeys = append(publicKeys, opts.PubKey)
+	}
+	publicKeys = append(publicKeys, opts.PubKeys...)
+	if len(publicKeys) == 0 {
+		km.discover = true
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		km.stopWatch = cancel
+		go km.watchKeys(watchCtx)
+		return km, nil
+	}
+
+	keys, err := newKeySet(publicKeys)
 	if err != nil {
-		return nil, NewGenericError(err, "failed to hex decode public key '%s'", opts.PubKey)
-	}
-
-	return &KeyManager{
-		remoteAddress: opts.Location,
-		accessToken:   opts.AccessToken,
-		originPubKey:  opts.PubKey,
-		pubKey:        bytesutil.ToBytes48(decodedPubKey),
-		network:       opts.Network,
-		httpClient:    httpex.CreateClient(),
-		log:           log,
-	}, nil
+		return nil, err
+	}
+	km.keys = keys
+	km.originPubKey = keys[keys.sorted()[0]]
+	if len(opts.PubKey) > 0 {
+		km.originPubKey = strings.ToLower(strings.TrimPrefix(opts.PubKey, "0x"))
+	}
+
+	return km, nil
+}
+
+// Close stops refreshing the discovered accounts.
+func (km *KeyManager) Close() {
+	if km.stopWatch != nil {
+		km.stopWatch()
+	}
 }
 
 // FetchValidatingKeys implements KeyManager interface.
+// The accounts of the mount are discovered on first use if no public key is configured.
 func (km *KeyManager) FetchValidatingKeys() ([][48]byte, error) {
-	return [][48]byte{km.pubKey}, nil
+	return km.validatingKeys()
 }
 
 // Sign implements KeyManager interface.
@@ -83,13 +170,14 @@ func (km *KeyManager) Sign(ctx context.Context, pubKey [48]byte, root [32]byte)
 
 // SignGeneric implements ProtectingKeyManager interface.
 func (km *KeyManager) SignGeneric(pubKey [48]byte, root [32]byte, domain [32]byte) (bls.Signature, error) {
-	if pubKey != km.pubKey {
-		return nil, ErrNoSuchKey
+	publicKey, err := km.publicKey(pubKey)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare request body.
 	req := SignAggregationRequest{
-		PubKey:     km.originPubKey,
+		PubKey:     publicKey,
 		Domain:     hex.EncodeToString(domain[:]),
 		DataToSign: hex.EncodeToString(root[:]),
 	}
@@ -104,7 +192,7 @@ func (km *KeyManager) SignGeneric(pubKey [48]byte, root [32]byte, domain [32]byt
 	var resp SignResponse
 	if err := km.sendRequest(http.MethodPost, backend.SignAggregationPattern, reqBody, &resp); err != nil {
 		km.log.WithError(err).Error("failed to send sign aggregation request")
-		return nil, NewGenericError(err, "failed to send SignGeneric request to remote vault wallet")
+		return nil, requestError(err, "failed to send SignGeneric request to remote vault wallet")
 	}
 
 	// Signature is base64 encoded, so we have to decode that.
@@ -124,13 +212,14 @@ func (km *KeyManager) SignGeneric(pubKey [48]byte, root [32]byte, domain [32]byt
 
 // SignProposal implements ProtectingKeyManager interface.
 func (km *KeyManager) SignProposal(pubKey [48]byte, domain [32]byte, data *ethpb.BeaconBlockHeader) (bls.Signature, error) {
-	if pubKey != km.pubKey {
-		return nil, ErrNoSuchKey
+	publicKey, err := km.publicKey(pubKey)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare request body.
 	req := SignProposalRequest{
-		PubKey:        km.originPubKey,
+		PubKey:        publicKey,
 		Domain:        hex.EncodeToString(domain[:]),
 		Slot:          data.GetSlot(),
 		ProposerIndex: data.GetProposerIndex(),
@@ -149,7 +238,7 @@ func (km *KeyManager) SignProposal(pubKey [48]byte, domain [32]byte, data *ethpb
 	var resp SignResponse
 	if err := km.sendRequest(http.MethodPost, backend.SignProposalPattern, reqBody, &resp); err != nil {
 		km.log.WithError(err).Error("failed to send sign proposal request")
-		return nil, NewGenericError(err, "failed to send SignAttestation request to remote vault wallet")
+		return nil, requestError(err, "failed to send SignAttestation request to remote vault wallet")
 	}
 
 	// Signature is base64 encoded, so we have to decode that.
@@ -169,13 +258,14 @@ func (km *KeyManager) SignProposal(pubKey [48]byte, domain [32]byte, data *ethpb
 
 // SignAttestation implements ProtectingKeyManager interface.
 func (km *KeyManager) SignAttestation(pubKey [48]byte, domain [32]byte, data *ethpb.AttestationData) (bls.Signature, error) {
-	if pubKey != km.pubKey {
-		return nil, ErrNoSuchKey
+	publicKey, err := km.publicKey(pubKey)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare request body.
 	req := SignAttestationRequest{
-		PubKey:          km.originPubKey,
+		PubKey:          publicKey,
 		Domain:          hex.EncodeToString(domain[:]),
 		Slot:            data.GetSlot(),
 		CommitteeIndex:  data.GetCommitteeIndex(),
@@ -196,7 +286,7 @@ func (km *KeyManager) SignAttestation(pubKey [48]byte, domain [32]byte, data *et
 	var resp SignResponse
 	if err := km.sendRequest(http.MethodPost, backend.SignAttestationPattern, reqBody, &resp); err != nil {
 		km.log.WithError(err).Error("failed to send sign attestation request")
-		return nil, NewGenericError(err, "failed to send SignAttestation request to remote vault wallet")
+		return nil, requestError(err, "failed to send SignAttestation request to remote vault wallet")
 	}
 
 	// Signature is base64 encoded, so we have to decode that.
@@ -214,10 +304,162 @@ func (km *KeyManager) SignAttestation(pubKey [48]byte, domain [32]byte, data *et
 	return sig, nil
 }
 
+// FetchMetadata returns the metadata of the configured account, the first one if several are configured.
+// Returns ErrNoSuchKey if the accounts are discovered.
+func (km *KeyManager) FetchMetadata() (*AccountMetadataModel, error) {
+	if len(km.originPubKey) == 0 {
+		return nil, ErrNoSuchKey
+	}
+
+	return km.fetchMetadata(km.originPubKey)
+}
+
+// FetchAccountMetadata returns the metadata of the given account.
+func (km *KeyManager) FetchAccountMetadata(pubKey [48]byte) (*AccountMetadataModel, error) {
+	publicKey, err := km.publicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return km.fetchMetadata(publicKey)
+}
+
+func (km *KeyManager) fetchMetadata(publicKey string) (*AccountMetadataModel, error) {
+	var resp AccountMetadataResponse
+	if err := km.sendRequest(http.MethodGet, "accounts/"+publicKey+"/metadata", nil, &resp); err != nil {
+		km.log.WithError(err).Error("failed to send account metadata request")
+		return nil, requestError(err, "failed to send account metadata request to remote vault wallet")
+	}
+
+	return &resp.Data, nil
+}
+
+// FetchFeeRecipient returns the fee recipient of the account.
+// Returns a zero address if the fee recipient is not set.
+func (km *KeyManager) FetchFeeRecipient() ([20]byte, error) {
+	var ret [20]byte
+	metadata, err := km.FetchMetadata()
+	if err != nil {
+		return ret, err
+	}
+
+	if len(metadata.FeeRecipient) == 0 {
+		return ret, nil
+	}
+
+	address, err := hex.DecodeString(strings.TrimPrefix(metadata.FeeRecipient, "0x"))
+	if err != nil || len(address) != len(ret) {
+		return ret, NewGenericErrorMessage("invalid fee recipient %s", metadata.FeeRecipient)
+	}
+	copy(ret[:], address)
+
+	return ret, nil
+}
+
+// FetchGraffiti returns the graffiti of the account.
+func (km *KeyManager) FetchGraffiti() ([]byte, error) {
+	metadata, err := km.FetchMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(metadata.Graffiti), nil
+}
+
+// FetchHealth returns the health of the remote vault mount.
+// The health of a mount that is not ready is returned as well.
+func (km *KeyManager) FetchHealth() (*HealthModel, error) {
+	var resp HealthResponse
+	if err := km.sendRequestWithClient(km.probeClient, http.MethodGet, backend.HealthPattern, nil, &resp); err != nil {
+		httpErr, ok := err.(*HTTPRequestError)
+		if !ok || httpErr.StatusCode != http.StatusServiceUnavailable {
+			km.log.WithError(err).Error("failed to send health request")
+			return nil, NewGenericError(err, "failed to send health request to remote vault wallet")
+		}
+
+		if err := json.Unmarshal(httpErr.ResponseBody, &resp); err != nil {
+			return nil, NewGenericError(err, "failed to decode health response body")
+		}
+	}
+
+	return &resp.Data, nil
+}
+
+// CheckHealth returns an error if the remote vault mount is not ready or an account can't sign.
+// The configured accounts must exist and be active, the discovered ones are listed.
+// It is meant to be called on startup, before signing.
+func (km *KeyManager) CheckHealth() error {
+	health, err := km.FetchHealth()
+	if err != nil {
+		return err
+	}
+
+	if !health.Ready {
+		return NewGenericErrorMessage("remote vault wallet is not ready: %s", strings.Join(health.Reasons, ", "))
+	}
+
+	if km.discover {
+		return km.RefreshKeys()
+	}
+
+	km.keysLock.RLock()
+	publicKeys := km.keys.publicKeys()
+	km.keysLock.RUnlock()
+	for _, publicKey := range publicKeys {
+		// The account must exist on the mount
+		var resp AccountMetadataResponse
+		if err := km.sendRequest(http.MethodGet, "accounts/"+publicKey+"/metadata", nil, &resp); err != nil {
+			if errors.Is(err, ErrAccountNotFound) {
+				return ErrNoSuchKey
+			}
+			return requestError(err, "failed to send account metadata request to remote vault wallet")
+		}
+
+		if resp.Data.Status == "paused" {
+			return NewGenericErrorMessage("account %s is paused", publicKey)
+		}
+	}
+
+	return nil
+}
+
+// requestError returns the errors of the remote vault as is, so they can be compared with errors.Is.
+func requestError(err error, desc string) error {
+	if serviceErr, ok := err.(*ServiceError); ok {
+		return serviceErr
+	}
+	return NewGenericError(err, desc)
+}
+
 // sendRequest implements the logic to work with HTTP requests.
 func (km *KeyManager) sendRequest(method, path string, reqBody []byte, respBody interface{}) error {
+	return km.sendRequestWithClient(km.httpClient, method, path, reqBody, respBody)
+}
+
+// sendRequestWithClient sends the HTTP request with the given client.
+// A request denied by Vault is sent once more with a new token.
+func (km *KeyManager) sendRequestWithClient(client *http.Client, method, path string, reqBody []byte, respBody interface{}) error {
 	endpoint := km.remoteAddress + endpoint.Build(km.network, path)
 
+	token, err := km.auth.Token()
+	if err != nil {
+		return NewGenericError(err, "failed to get remote vault token")
+	}
+
+	err = km.doRequest(client, method, endpoint, token, reqBody, respBody)
+	if httpErr, ok := err.(*HTTPRequestError); ok && httpErr.StatusCode == http.StatusForbidden && km.auth.Invalidate(token) {
+		km.log.Warn("remote vault denied the token, logging in again")
+		if token, err = km.auth.Token(); err != nil {
+			return NewGenericError(err, "failed to get remote vault token")
+		}
+		return km.doRequest(client, method, endpoint, token, reqBody, respBody)
+	}
+
+	return err
+}
+
+// doRequest sends the HTTP request with the given token.
+func (km *KeyManager) doRequest(client *http.Client, method, endpoint, token string, reqBody []byte, respBody interface{}) error {
 	// Prepare a new request
 	req, err := http.NewRequest(method, endpoint, bytes.NewBuffer(reqBody))
 	if err != nil {
@@ -225,11 +467,11 @@ func (km *KeyManager) sendRequest(method, path string, reqBody []byte, respBody
 	}
 
 	// Pass auth token.
-	req.Header.Set("Authorization", "Bearer "+km.accessToken)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request.
-	resp, err := km.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return NewGenericError(err, "failed to send HTTP request")
 	}
@@ -242,6 +484,12 @@ func (km *KeyManager) sendRequest(method, path string, reqBody []byte, respBody
 			km.log.WithError(err).Error("failed to read error response body")
 		}
 
+		// Errors with a code are returned typed
+		var errResp ErrorResponse
+		if err := json.Unmarshal(responseBody, &errResp); err == nil && len(errResp.Data.Code) > 0 {
+			return NewServiceError(errResp.Data.Code, resp.StatusCode, errResp.Data.Message)
+		}
+
 		return NewHTTPRequestError(endpoint, resp.StatusCode, responseBody, "unexpected status code")
 	}
 
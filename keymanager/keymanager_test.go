@@ -1,13 +1,28 @@
 package keymanager_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 	ethpb "github.com/prysmaticlabs/ethereumapis/eth/v1alpha1"
@@ -19,6 +34,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/bloxapp/key-vault/keymanager"
+	"github.com/bloxapp/key-vault/utils/errorex"
 )
 
 const (
@@ -357,3 +373,861 @@ func newTestRemoteWallet(handler http.HandlerFunc) *httptest.Server {
 
 	return s
 }
+
+// writeCodedError writes the response of the vault to an error with the given code.
+func writeCodedError(t *testing.T, writer http.ResponseWriter, code errorex.ErrorCode, message string) {
+	writer.WriteHeader(code.StatusCode())
+	require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{
+		Data: map[string]interface{}{
+			"message":     message,
+			"status_code": code.StatusCode(),
+			"code":        code,
+		},
+	}))
+}
+
+func TestServiceErrors(t *testing.T) {
+	accountPubKey, err := hex.DecodeString(defaultAccountPublicKey)
+	require.NoError(t, err)
+
+	var code errorex.ErrorCode
+	s := newTestRemoteWallet(func(writer http.ResponseWriter, request *http.Request) {
+		writeCodedError(t, writer, code, "failed with "+string(code))
+	})
+	defer s.Close()
+
+	wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+		Location:    s.URL,
+		AccessToken: defaultAccessToken,
+		PubKey:      defaultAccountPublicKey,
+		Network:     "test",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		code     errorex.ErrorCode
+		expected error
+	}{
+		{errorex.CodeSlashableDoubleVote, keymanager.ErrSlashableDoubleVote},
+		{errorex.CodeSlashableSurround, keymanager.ErrSlashableSurround},
+		{errorex.CodeDoubleProposal, keymanager.ErrDoubleProposal},
+		{errorex.CodeLocked, keymanager.ErrLocked},
+		{errorex.CodeAccountNotFound, keymanager.ErrAccountNotFound},
+		{errorex.CodeNotConfigured, keymanager.ErrNotConfigured},
+		{errorex.CodeBadRequest, keymanager.ErrBadRequest},
+		{errorex.CodeHalted, keymanager.ErrHalted},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.code), func(t *testing.T) {
+			code = test.code
+
+			_, err := wallet.SignAttestation(bytesutil.ToBytes48(accountPubKey), [32]byte{}, &ethpb.AttestationData{
+				Source: &ethpb.Checkpoint{},
+				Target: &ethpb.Checkpoint{},
+			})
+			require.True(t, errors.Is(err, test.expected), err.Error())
+			require.False(t, errors.Is(err, keymanager.ErrLocked) && test.code != errorex.CodeLocked)
+
+			var serviceErr *keymanager.ServiceError
+			require.True(t, errors.As(err, &serviceErr))
+			require.Equal(t, test.code.StatusCode(), serviceErr.StatusCode)
+			require.Equal(t, "failed with "+string(test.code), serviceErr.Message)
+
+			_, err = wallet.FetchMetadata()
+			require.True(t, errors.Is(err, test.expected), err.Error())
+		})
+	}
+}
+
+func TestKeyDiscovery(t *testing.T) {
+	const otherAccountPublicKey = "ab321d63b7b991107a5667bf4fe853a266c2baea87d33a41c7e39a5641bfd3b5434b76f1229d452acb45ba86284e3279"
+	const addedAccountPublicKey = "b8e9d3b4e1f3c8ab5b3ae5e5b2a1f0d2a8d6a55d8c0bfe9e1c3c1e1c1ab9c2a7e77a2c9b2d0f9f1d1a8c2a4e6c7d2e1f"
+
+	var protect sync.Mutex
+	accounts := []string{defaultAccountPublicKey, otherAccountPublicKey}
+	var signedWith []string
+	s := newTestRemoteWallet(func(writer http.ResponseWriter, request *http.Request) {
+		protect.Lock()
+		defer protect.Unlock()
+
+		switch request.URL.Path {
+		case "/v1/ethereum/test/accounts/":
+			require.Equal(t, "true", request.URL.Query().Get("list"))
+
+			// One account per page
+			var page []map[string]string
+			var nextCursor string
+			for i, publicKey := range accounts {
+				if publicKey <= request.URL.Query().Get("cursor") {
+					continue
+				}
+				page = append(page, map[string]string{"validationPubKey": publicKey})
+				if i < len(accounts)-1 {
+					nextCursor = publicKey
+				}
+				break
+			}
+
+			data := map[string]interface{}{"accounts": page}
+			if len(nextCursor) > 0 {
+				data["next_cursor"] = nextCursor
+			}
+			require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{Data: data}))
+		case "/v1/ethereum/test/accounts/sign-aggregation":
+			var req keymanager.SignAggregationRequest
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&req))
+			signedWith = append(signedWith, req.PubKey)
+			writeCodedError(t, writer, errorex.CodeBadRequest, "not signed")
+		default:
+			t.Fatalf("unexpected path %s", request.URL.Path)
+		}
+	})
+	defer s.Close()
+
+	decode := func(publicKey string) [48]byte {
+		decoded, err := hex.DecodeString(publicKey)
+		require.NoError(t, err)
+		return bytesutil.ToBytes48(decoded)
+	}
+
+	t.Run("sign with configured accounts", func(t *testing.T) {
+		wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location:    s.URL,
+			AccessToken: defaultAccessToken,
+			PubKeys:     []string{otherAccountPublicKey, defaultAccountPublicKey},
+			Network:     "test",
+		})
+		require.NoError(t, err)
+
+		keys, err := wallet.FetchValidatingKeys()
+		require.NoError(t, err)
+		require.Equal(t, [][48]byte{decode(defaultAccountPublicKey), decode(otherAccountPublicKey)}, keys)
+
+		signedWith = nil
+		for _, publicKey := range []string{otherAccountPublicKey, defaultAccountPublicKey} {
+			_, err := wallet.SignGeneric(decode(publicKey), [32]byte{}, [32]byte{})
+			require.True(t, errors.Is(err, keymanager.ErrBadRequest))
+		}
+		require.Equal(t, []string{otherAccountPublicKey, defaultAccountPublicKey}, signedWith)
+
+		_, err = wallet.SignGeneric(decode(addedAccountPublicKey), [32]byte{}, [32]byte{})
+		require.EqualError(t, err, keymanager.ErrNoSuchKey.Error())
+	})
+
+	t.Run("reject invalid refresh interval", func(t *testing.T) {
+		_, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location:        s.URL,
+			AccessToken:     defaultAccessToken,
+			Network:         "test",
+			RefreshInterval: "sometimes",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("discover accounts", func(t *testing.T) {
+		wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location:        s.URL,
+			AccessToken:     defaultAccessToken,
+			Network:         "test",
+			RefreshInterval: "10ms",
+		})
+		require.NoError(t, err)
+		defer wallet.Close()
+
+		keys, err := wallet.FetchValidatingKeys()
+		require.NoError(t, err)
+		require.Equal(t, [][48]byte{decode(defaultAccountPublicKey), decode(otherAccountPublicKey)}, keys)
+
+		signedWith = nil
+		_, err = wallet.SignGeneric(decode(otherAccountPublicKey), [32]byte{}, [32]byte{})
+		require.True(t, errors.Is(err, keymanager.ErrBadRequest))
+		require.Equal(t, []string{otherAccountPublicKey}, signedWith)
+
+		_, err = wallet.FetchMetadata()
+		require.EqualError(t, err, keymanager.ErrNoSuchKey.Error())
+
+		// Add an account and remove another, the accounts are refreshed in the background
+		changes := make(chan [][48]byte, 1)
+		sub := wallet.SubscribeAccountChanges(changes)
+		defer sub.Unsubscribe()
+
+		protect.Lock()
+		accounts = []string{otherAccountPublicKey, addedAccountPublicKey}
+		protect.Unlock()
+
+		expected := [][48]byte{decode(otherAccountPublicKey), decode(addedAccountPublicKey)}
+		select {
+		case keys := <-changes:
+			require.Equal(t, expected, keys)
+		case <-time.After(5 * time.Second):
+			t.Fatal("account changes were not sent")
+		}
+
+		keys, err = wallet.FetchValidatingKeys()
+		require.NoError(t, err)
+		require.Equal(t, expected, keys)
+
+		_, err = wallet.SignGeneric(decode(defaultAccountPublicKey), [32]byte{}, [32]byte{})
+		require.EqualError(t, err, keymanager.ErrNoSuchKey.Error())
+
+		// Nothing is sent without changes
+		require.NoError(t, wallet.RefreshKeys())
+		require.Len(t, changes, 0)
+	})
+
+	t.Run("stop refreshing when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		wallet, err := keymanager.NewKeyManagerWithContext(ctx, logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location:        s.URL,
+			AccessToken:     defaultAccessToken,
+			Network:         "test",
+			RefreshInterval: "10ms",
+		})
+		require.NoError(t, err)
+
+		expected := [][48]byte{decode(otherAccountPublicKey), decode(addedAccountPublicKey)}
+		keys, err := wallet.FetchValidatingKeys()
+		require.NoError(t, err)
+		require.Equal(t, expected, keys)
+
+		cancel()
+		protect.Lock()
+		accounts = []string{otherAccountPublicKey}
+		protect.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		keys, err = wallet.FetchValidatingKeys()
+		require.NoError(t, err)
+		require.Equal(t, expected, keys)
+	})
+}
+
+// fakeVaultAuth is a Vault serving the auth endpoints and the account metadata, which requires a valid token.
+// Failures of the handler are kept to be reported by the test goroutine.
+type fakeVaultAuth struct {
+	sync.Mutex
+	tokens    map[string]bool
+	logins    []map[string]string
+	renewals  int
+	failRenew bool
+	failures  []error
+
+	// loginGate holds the logins until it is closed, loginStarted is sent a value for every login held
+	loginGate    chan struct{}
+	loginStarted chan struct{}
+}
+
+func (v *fakeVaultAuth) fail(err error) {
+	v.failures = append(v.failures, err)
+}
+
+func (v *fakeVaultAuth) encode(writer http.ResponseWriter, body interface{}) {
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		v.fail(err)
+	}
+}
+
+func (v *fakeVaultAuth) issue(writer http.ResponseWriter, token string) {
+	v.tokens[token] = true
+	v.encode(writer, map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token":   token,
+			"lease_duration": 1,
+			"renewable":      true,
+		},
+	})
+}
+
+func (v *fakeVaultAuth) deny(writer http.ResponseWriter) {
+	writer.WriteHeader(http.StatusForbidden)
+	v.encode(writer, map[string]interface{}{
+		"errors": []string{"permission denied"},
+	})
+}
+
+func (v *fakeVaultAuth) handle(writer http.ResponseWriter, request *http.Request) {
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	switch request.URL.Path {
+	case "/v1/auth/approle/login", "/v1/auth/k8s/login":
+		var body map[string]string
+		err := json.NewDecoder(request.Body).Decode(&body)
+
+		v.Lock()
+		gate, started := v.loginGate, v.loginStarted
+		v.Unlock()
+		if gate != nil {
+			started <- struct{}{}
+			<-gate
+		}
+
+		v.Lock()
+		defer v.Unlock()
+		if err != nil {
+			v.fail(err)
+		}
+		v.logins = append(v.logins, body)
+		v.issue(writer, fmt.Sprintf("token-%d", len(v.logins)))
+	case "/v1/auth/token/renew-self":
+		v.Lock()
+		defer v.Unlock()
+		if v.failRenew || !v.tokens[token] {
+			v.deny(writer)
+			return
+		}
+		v.renewals++
+		v.issue(writer, token)
+	case "/v1/ethereum/test/accounts/" + defaultAccountPublicKey + "/metadata":
+		v.Lock()
+		defer v.Unlock()
+		if !v.tokens[token] {
+			v.deny(writer)
+			return
+		}
+		v.encode(writer, &logical.Response{
+			Data: map[string]interface{}{
+				"graffiti": token,
+			},
+		})
+	default:
+		v.Lock()
+		defer v.Unlock()
+		v.fail(fmt.Errorf("unexpected path %s", request.URL.Path))
+		writer.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// requireNoFailures fails the test if the handler failed.
+func (v *fakeVaultAuth) requireNoFailures(t *testing.T) {
+	v.Lock()
+	defer v.Unlock()
+	require.Empty(t, v.failures)
+}
+
+func (v *fakeVaultAuth) loginCount() int {
+	v.Lock()
+	defer v.Unlock()
+	return len(v.logins)
+}
+
+// holdLogins holds the next logins until the returned function is called.
+func (v *fakeVaultAuth) holdLogins() (started chan struct{}, release func()) {
+	v.Lock()
+	defer v.Unlock()
+	v.loginGate = make(chan struct{})
+	v.loginStarted = make(chan struct{}, 10)
+
+	gate := v.loginGate
+	return v.loginStarted, func() {
+		v.Lock()
+		v.loginGate = nil
+		v.Unlock()
+		close(gate)
+	}
+}
+
+// fakeClock is a clock moved forward by the test.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestAuth(t *testing.T) {
+	vault := &fakeVaultAuth{tokens: map[string]bool{}}
+	s := newTestRemoteWallet(vault.handle)
+	defer s.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	newWallet := func(auth *keymanager.AuthConfig) *keymanager.KeyManager {
+		wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location: s.URL,
+			PubKey:   defaultAccountPublicKey,
+			Network:  "test",
+			Auth:     auth,
+		})
+		require.NoError(t, err)
+		keymanager.SetClock(wallet, clock.Now)
+		return wallet
+	}
+
+	// graffiti returns the token the metadata was read with
+	graffiti := func(t *testing.T, wallet *keymanager.KeyManager) string {
+		ret, err := wallet.FetchGraffiti()
+		require.NoError(t, err)
+		return string(ret)
+	}
+
+	t.Run("reject incomplete config", func(t *testing.T) {
+		_, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location: s.URL,
+			PubKey:   defaultAccountPublicKey,
+			Auth:     &keymanager.AuthConfig{Method: keymanager.AuthMethodAppRole, RoleID: "role"},
+		})
+		require.Error(t, err)
+
+		_, err = keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location: s.URL,
+			PubKey:   defaultAccountPublicKey,
+			Auth:     &keymanager.AuthConfig{Method: "userpass"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("approle renewal and expiry", func(t *testing.T) {
+		wallet := newWallet(&keymanager.AuthConfig{
+			Method:   keymanager.AuthMethodAppRole,
+			RoleID:   "role",
+			SecretID: "secret",
+		})
+
+		require.Equal(t, "token-1", graffiti(t, wallet))
+		vault.Lock()
+		require.Equal(t, map[string]string{"role_id": "role", "secret_id": "secret"}, vault.logins[0])
+		vault.Unlock()
+
+		// The token is renewed in the background after two thirds of the lease
+		clock.Add(700 * time.Millisecond)
+		require.Equal(t, "token-1", graffiti(t, wallet))
+		require.Eventually(t, func() bool {
+			vault.Lock()
+			defer vault.Unlock()
+			return vault.renewals == 1
+		}, time.Second, 10*time.Millisecond)
+
+		// A revoked token is replaced on 403
+		vault.Lock()
+		delete(vault.tokens, "token-1")
+		vault.Unlock()
+		require.Equal(t, "token-2", graffiti(t, wallet))
+
+		// An expired token is replaced before sending
+		vault.Lock()
+		vault.failRenew = true
+		vault.Unlock()
+		clock.Add(1100 * time.Millisecond)
+		require.Equal(t, "token-3", graffiti(t, wallet))
+		require.Equal(t, 3, vault.loginCount())
+
+		vault.requireNoFailures(t)
+	})
+
+	t.Run("serve the current token while logging in again", func(t *testing.T) {
+		wallet := newWallet(&keymanager.AuthConfig{
+			Method:   keymanager.AuthMethodAppRole,
+			RoleID:   "role",
+			SecretID: "secret",
+		})
+		token := graffiti(t, wallet)
+		logins := vault.loginCount()
+
+		// The renewal fails, the login is held
+		vault.Lock()
+		vault.failRenew = true
+		vault.Unlock()
+		started, release := vault.holdLogins()
+
+		clock.Add(700 * time.Millisecond)
+		require.Equal(t, token, graffiti(t, wallet))
+		<-started
+		require.Equal(t, token, graffiti(t, wallet))
+
+		release()
+		require.Eventually(t, func() bool {
+			return vault.loginCount() == logins+1
+		}, time.Second, 10*time.Millisecond)
+		require.Eventually(t, func() bool {
+			ret, err := wallet.FetchGraffiti()
+			return err == nil && string(ret) != token
+		}, time.Second, 10*time.Millisecond)
+
+		vault.requireNoFailures(t)
+	})
+
+	t.Run("log in once for concurrent requests", func(t *testing.T) {
+		wallet := newWallet(&keymanager.AuthConfig{
+			Method:   keymanager.AuthMethodAppRole,
+			RoleID:   "role",
+			SecretID: "secret",
+		})
+		graffiti(t, wallet)
+		logins := vault.loginCount()
+
+		// The token expires, the login is held until all requests wait for it
+		started, release := vault.holdLogins()
+		clock.Add(1100 * time.Millisecond)
+
+		const requests = 5
+		tokens := make(chan string, requests)
+		errs := make(chan error, requests)
+		for i := 0; i < requests; i++ {
+			go func() {
+				ret, err := wallet.FetchGraffiti()
+				if err != nil {
+					errs <- err
+					return
+				}
+				tokens <- string(ret)
+			}()
+		}
+		<-started
+		release()
+
+		expected := fmt.Sprintf("token-%d", logins+1)
+		for i := 0; i < requests; i++ {
+			select {
+			case err := <-errs:
+				require.NoError(t, err)
+			case token := <-tokens:
+				require.Equal(t, expected, token)
+			}
+		}
+		require.Equal(t, logins+1, vault.loginCount())
+
+		vault.requireNoFailures(t)
+	})
+
+	t.Run("kubernetes", func(t *testing.T) {
+		jwtFile, err := ioutil.TempFile("", "jwt")
+		require.NoError(t, err)
+		defer os.Remove(jwtFile.Name())
+		_, err = jwtFile.WriteString("service-account-jwt\n")
+		require.NoError(t, err)
+		require.NoError(t, jwtFile.Close())
+
+		wallet := newWallet(&keymanager.AuthConfig{
+			Method:  keymanager.AuthMethodKubernetes,
+			Mount:   "k8s",
+			Role:    "validator",
+			JWTFile: jwtFile.Name(),
+		})
+
+		graffiti(t, wallet)
+		vault.Lock()
+		require.Equal(t, map[string]string{"role": "validator", "jwt": "service-account-jwt"}, vault.logins[len(vault.logins)-1])
+		vault.Unlock()
+
+		vault.requireNoFailures(t)
+	})
+
+	t.Run("token file", func(t *testing.T) {
+		tokenFile, err := ioutil.TempFile("", "token")
+		require.NoError(t, err)
+		defer os.Remove(tokenFile.Name())
+		require.NoError(t, tokenFile.Close())
+
+		vault.Lock()
+		vault.tokens["file-token-1"] = true
+		vault.Unlock()
+		require.NoError(t, ioutil.WriteFile(tokenFile.Name(), []byte("file-token-1"), 0600))
+
+		wallet := newWallet(&keymanager.AuthConfig{
+			Method:    keymanager.AuthMethodTokenFile,
+			TokenFile: tokenFile.Name(),
+		})
+		require.Equal(t, "file-token-1", graffiti(t, wallet))
+
+		// The file is read again once the token is denied
+		vault.Lock()
+		delete(vault.tokens, "file-token-1")
+		vault.tokens["file-token-2"] = true
+		vault.Unlock()
+		require.NoError(t, ioutil.WriteFile(tokenFile.Name(), []byte("file-token-2"), 0600))
+		require.Equal(t, "file-token-2", graffiti(t, wallet))
+
+		vault.requireNoFailures(t)
+	})
+}
+
+// newTestCA returns a self-signed CA certificate and its key.
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return ca, key
+}
+
+// writeTestClientCertificate writes a client certificate signed by the given CA and its key to the given PEM files.
+func writeTestClientCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	// The server requires a client certificate of the client CA, its own certificate is valid for example.com
+	clientCA, clientCAKey := newTestCA(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA)
+
+	var clients []string
+	var protect sync.Mutex
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		protect.Lock()
+		clients = append(clients, request.TLS.PeerCertificates[0].Subject.CommonName)
+		protect.Unlock()
+
+		require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{
+			Data: map[string]interface{}{
+				"graffiti": "key-vault",
+			},
+		}))
+	}))
+	s.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MaxVersion: tls.VersionTLS12,
+	}
+	s.Config.SetKeepAlivesEnabled(false)
+	s.StartTLS()
+	defer s.Close()
+
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+	writeTestClientCertificate(t, clientCA, clientCAKey, "client-1", certFile, keyFile)
+
+	newWallet := func(tlsConfig *keymanager.TLSConfig) (*keymanager.KeyManager, error) {
+		return keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+			Location:    s.URL,
+			AccessToken: defaultAccessToken,
+			PubKey:      defaultAccountPublicKey,
+			Network:     "test",
+			TLS:         tlsConfig,
+		})
+	}
+
+	t.Run("reject invalid config", func(t *testing.T) {
+		_, err := newWallet(&keymanager.TLSConfig{CertFile: certFile})
+		require.Error(t, err)
+
+		_, err = newWallet(&keymanager.TLSConfig{MinVersion: "1.0"})
+		require.Error(t, err)
+
+		_, err = newWallet(&keymanager.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")})
+		require.Error(t, err)
+	})
+
+	t.Run("reject unknown server", func(t *testing.T) {
+		wallet, err := newWallet(&keymanager.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+		_, err = wallet.FetchGraffiti()
+		require.Error(t, err)
+	})
+
+	t.Run("reject wrong server name", func(t *testing.T) {
+		wallet, err := newWallet(&keymanager.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "vault.example.org"})
+		require.NoError(t, err)
+		_, err = wallet.FetchGraffiti()
+		require.Error(t, err)
+	})
+
+	t.Run("reject without client certificate", func(t *testing.T) {
+		wallet, err := newWallet(&keymanager.TLSConfig{CAFile: caFile})
+		require.NoError(t, err)
+		_, err = wallet.FetchGraffiti()
+		require.Error(t, err)
+	})
+
+	t.Run("reject lower version", func(t *testing.T) {
+		wallet, err := newWallet(&keymanager.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+		require.NoError(t, err)
+		_, err = wallet.FetchGraffiti()
+		require.Error(t, err)
+	})
+
+	t.Run("mutual TLS with rotated certificate", func(t *testing.T) {
+		wallet, err := newWallet(&keymanager.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"})
+		require.NoError(t, err)
+
+		protect.Lock()
+		clients = nil
+		protect.Unlock()
+		_, err = wallet.FetchGraffiti()
+		require.NoError(t, err)
+
+		// Rotate the client certificate, the modification time must change
+		writeTestClientCertificate(t, clientCA, clientCAKey, "client-2", certFile, keyFile)
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certFile, future, future))
+		require.NoError(t, os.Chtimes(keyFile, future, future))
+
+		_, err = wallet.FetchGraffiti()
+		require.NoError(t, err)
+
+		protect.Lock()
+		defer protect.Unlock()
+		require.Equal(t, []string{"client-1", "client-2"}, clients)
+	})
+}
+
+func TestFetchMetadata(t *testing.T) {
+	var statusCode int
+	s := newTestRemoteWallet(func(writer http.ResponseWriter, request *http.Request) {
+		require.Equal(t, http.MethodGet, request.Method)
+		require.Equal(t, "/v1/ethereum/test/accounts/"+defaultAccountPublicKey+"/metadata", request.URL.Path)
+
+		if statusCode != http.StatusOK {
+			writer.WriteHeader(statusCode)
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{
+			Data: map[string]interface{}{
+				"labels":        map[string]string{"owner": "alice"},
+				"fee_recipient": "0x5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c",
+				"graffiti":      "key-vault",
+			},
+		}))
+	})
+	defer s.Close()
+
+	wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+		Location:    s.URL,
+		AccessToken: defaultAccessToken,
+		PubKey:      defaultAccountPublicKey,
+		Network:     "test",
+	})
+	require.NoError(t, err)
+
+	t.Run("successfully fetched metadata", func(t *testing.T) {
+		statusCode = http.StatusOK
+		metadata, err := wallet.FetchMetadata()
+		require.NoError(t, err)
+		require.Equal(t, "alice", metadata.Labels["owner"])
+
+		feeRecipient, err := wallet.FetchFeeRecipient()
+		require.NoError(t, err)
+		require.Equal(t, "5a0b54d5dc17e0aadc383d2db43b0a0d3e029c4c", hex.EncodeToString(feeRecipient[:]))
+
+		graffiti, err := wallet.FetchGraffiti()
+		require.NoError(t, err)
+		require.Equal(t, []byte("key-vault"), graffiti)
+	})
+
+	t.Run("rejects with denied", func(t *testing.T) {
+		statusCode = http.StatusUnauthorized
+		_, err := wallet.FetchFeeRecipient()
+		require.Error(t, err)
+	})
+}
+
+func TestCheckHealth(t *testing.T) {
+	var healthStatusCode int
+	var reasons []string
+	var accountStatus string
+	s := newTestRemoteWallet(func(writer http.ResponseWriter, request *http.Request) {
+		require.Equal(t, http.MethodGet, request.Method)
+
+		switch request.URL.Path {
+		case "/v1/ethereum/test/health":
+			writer.WriteHeader(healthStatusCode)
+			require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{
+				Data: map[string]interface{}{
+					"ready":    len(reasons) == 0,
+					"reasons":  reasons,
+					"network":  "test",
+					"wallet":   true,
+					"accounts": 1,
+				},
+			}))
+		case "/v1/ethereum/test/accounts/" + defaultAccountPublicKey + "/metadata":
+			if len(accountStatus) == 0 {
+				writeCodedError(t, writer, errorex.CodeAccountNotFound, "account not found")
+				return
+			}
+			require.NoError(t, json.NewEncoder(writer).Encode(&logical.Response{
+				Data: map[string]interface{}{
+					"status": accountStatus,
+				},
+			}))
+		default:
+			t.Fatalf("unexpected path %s", request.URL.Path)
+		}
+	})
+	defer s.Close()
+
+	wallet, err := keymanager.NewKeyManager(logrus.NewEntry(logrus.New()), &keymanager.Config{
+		Location:    s.URL,
+		AccessToken: defaultAccessToken,
+		PubKey:      defaultAccountPublicKey,
+		Network:     "test",
+	})
+	require.NoError(t, err)
+
+	t.Run("ready", func(t *testing.T) {
+		healthStatusCode, reasons, accountStatus = http.StatusOK, nil, "active"
+		require.NoError(t, wallet.CheckHealth())
+
+		health, err := wallet.FetchHealth()
+		require.NoError(t, err)
+		require.True(t, health.Ready)
+		require.Equal(t, 1, health.Accounts)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		healthStatusCode, reasons, accountStatus = http.StatusServiceUnavailable, []string{"wallet has no accounts"}, "active"
+		health, err := wallet.FetchHealth()
+		require.NoError(t, err)
+		require.False(t, health.Ready)
+
+		err = wallet.CheckHealth()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wallet has no accounts")
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		healthStatusCode, reasons, accountStatus = http.StatusOK, nil, ""
+		require.Equal(t, keymanager.ErrNoSuchKey, wallet.CheckHealth())
+	})
+
+	t.Run("paused account", func(t *testing.T) {
+		healthStatusCode, reasons, accountStatus = http.StatusOK, nil, "paused"
+		require.Error(t, wallet.CheckHealth())
+	})
+}
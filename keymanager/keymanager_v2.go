@@ -7,6 +7,7 @@ import (
 	validatorpb "github.com/prysmaticlabs/prysm/proto/validator/accounts/v2"
 	"github.com/prysmaticlabs/prysm/shared/bls"
 	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/event"
 	v2keymanager "github.com/prysmaticlabs/prysm/validator/keymanager/v2"
 )
 
@@ -27,14 +28,11 @@ func NewKeyManagerV2(km *KeyManager) *V2 {
 
 // Sign implements KeyManager-v2 interface.
 func (km *V2) Sign(ctx context.Context, req *validatorpb.SignRequest) (bls.Signature, error) {
-	if bytesutil.ToBytes48(req.GetPublicKey()) != km.km.pubKey {
-		return nil, ErrNoSuchKey
-	}
-
+	pubKey := bytesutil.ToBytes48(req.GetPublicKey())
 	domain := bytesutil.ToBytes32(req.GetSignatureDomain())
 	switch data := req.GetObject().(type) {
 	case *validatorpb.SignRequest_Block:
-		return km.km.SignProposal(km.km.pubKey, domain, &ethpb.BeaconBlockHeader{
+		return km.km.SignProposal(pubKey, domain, &ethpb.BeaconBlockHeader{
 			Slot:          data.Block.GetSlot(),
 			ProposerIndex: data.Block.GetProposerIndex(),
 			StateRoot:     data.Block.GetStateRoot(),
@@ -42,11 +40,11 @@ func (km *V2) Sign(ctx context.Context, req *validatorpb.SignRequest) (bls.Signa
 			BodyRoot:      req.GetSigningRoot(),
 		})
 	case *validatorpb.SignRequest_AttestationData:
-		return km.km.SignAttestation(km.km.pubKey, domain, data.AttestationData)
+		return km.km.SignAttestation(pubKey, domain, data.AttestationData)
 	case *validatorpb.SignRequest_AggregateAttestationAndProof:
-		return km.km.SignGeneric(km.km.pubKey, bytesutil.ToBytes32(req.GetSigningRoot()), domain)
+		return km.km.SignGeneric(pubKey, bytesutil.ToBytes32(req.GetSigningRoot()), domain)
 	case *validatorpb.SignRequest_Slot:
-		return km.km.SignGeneric(km.km.pubKey, bytesutil.ToBytes32(req.GetSigningRoot()), domain)
+		return km.km.SignGeneric(pubKey, bytesutil.ToBytes32(req.GetSigningRoot()), domain)
 	default:
 		return nil, ErrUnsupportedSigning
 	}
@@ -54,5 +52,30 @@ func (km *V2) Sign(ctx context.Context, req *validatorpb.SignRequest) (bls.Signa
 
 // FetchValidatingPublicKeys implements KeyManager-v2 interface.
 func (km *V2) FetchValidatingPublicKeys(_ context.Context) ([][48]byte, error) {
-	return [][48]byte{km.km.pubKey}, nil
+	return km.km.FetchValidatingKeys()
+}
+
+// SubscribeAccountChanges subscribes the given channel to the public keys of the discovered accounts.
+func (km *V2) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	return km.km.SubscribeAccountChanges(pubKeysChan)
+}
+
+// Close stops refreshing the discovered accounts.
+func (km *V2) Close() {
+	km.km.Close()
+}
+
+// FetchFeeRecipient returns the fee recipient of the account.
+func (km *V2) FetchFeeRecipient(_ context.Context) ([20]byte, error) {
+	return km.km.FetchFeeRecipient()
+}
+
+// FetchGraffiti returns the graffiti of the account.
+func (km *V2) FetchGraffiti(_ context.Context) ([]byte, error) {
+	return km.km.FetchGraffiti()
+}
+
+// CheckHealth returns an error if the remote vault mount is not ready or an account can't sign.
+func (km *V2) CheckHealth(_ context.Context) error {
+	return km.km.CheckHealth()
 }
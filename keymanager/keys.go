@@ -0,0 +1,214 @@
+package keymanager
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/event"
+
+	"github.com/bloxapp/key-vault/backend"
+)
+
+// Key discovery settings
+const (
+	// defaultRefreshInterval is how often the discovered accounts are refreshed if not configured
+	defaultRefreshInterval = time.Minute
+
+	// discoveryPageSize is the number of accounts listed per request
+	discoveryPageSize = 500
+)
+
+// decodePublicKey decodes the given HEX encoded public key.
+func decodePublicKey(publicKey string) ([48]byte, error) {
+	var ret [48]byte
+	decoded, err := hex.DecodeString(strings.TrimPrefix(publicKey, "0x"))
+	if err != nil {
+		return ret, NewGenericError(err, "failed to hex decode public key '%s'", publicKey)
+	}
+	if len(decoded) != len(ret) {
+		return ret, NewGenericErrorMessage("invalid public key length '%s'", publicKey)
+	}
+
+	return bytesutil.ToBytes48(decoded), nil
+}
+
+// keySet holds the accounts the key manager signs with, by public key.
+type keySet map[[48]byte]string
+
+// newKeySet returns the key set of the given HEX encoded public keys.
+func newKeySet(publicKeys []string) (keySet, error) {
+	ret := make(keySet, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		decoded, err := decodePublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		ret[decoded] = strings.ToLower(strings.TrimPrefix(publicKey, "0x"))
+	}
+
+	return ret, nil
+}
+
+// sorted returns the public keys ordered by their HEX encoding.
+func (keys keySet) sorted() [][48]byte {
+	ret := make([][48]byte, 0, len(keys))
+	for pubKey := range keys {
+		ret = append(ret, pubKey)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return keys[ret[i]] < keys[ret[j]]
+	})
+	return ret
+}
+
+// publicKeys returns the HEX encoded public keys in order.
+func (keys keySet) publicKeys() []string {
+	ret := make([]string, 0, len(keys))
+	for _, pubKey := range keys.sorted() {
+		ret = append(ret, keys[pubKey])
+	}
+	return ret
+}
+
+// diff returns the public keys of the given set missing from this one, and the ones of this set missing from the given one.
+func (keys keySet) diff(other keySet) (added []string, removed []string) {
+	for pubKey, publicKey := range other {
+		if _, ok := keys[pubKey]; !ok {
+			added = append(added, publicKey)
+		}
+	}
+	for pubKey, publicKey := range keys {
+		if _, ok := other[pubKey]; !ok {
+			removed = append(removed, publicKey)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// publicKey returns the HEX encoded public key of an account the key manager signs with.
+func (km *KeyManager) publicKey(pubKey [48]byte) (string, error) {
+	km.keysLock.RLock()
+	defer km.keysLock.RUnlock()
+
+	publicKey, ok := km.keys[pubKey]
+	if !ok {
+		return "", ErrNoSuchKey
+	}
+	return publicKey, nil
+}
+
+// validatingKeys returns the public keys the key manager signs with.
+// The accounts of the mount are discovered on first use if no public key is configured.
+func (km *KeyManager) validatingKeys() ([][48]byte, error) {
+	km.keysLock.RLock()
+	loaded := km.keys != nil
+	km.keysLock.RUnlock()
+
+	if !loaded {
+		if err := km.RefreshKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	km.keysLock.RLock()
+	defer km.keysLock.RUnlock()
+	return km.keys.sorted(), nil
+}
+
+// RefreshKeys lists the accounts of the mount and reports the added and removed ones to the subscribers.
+// The validator client also picks them up the next time it fetches the validating keys.
+// It does nothing if the public keys are configured.
+func (km *KeyManager) RefreshKeys() error {
+	if !km.discover {
+		return nil
+	}
+
+	publicKeys, err := km.listAccounts()
+	if err != nil {
+		km.log.WithError(err).Error("failed to discover accounts")
+		return err
+	}
+
+	keys, err := newKeySet(publicKeys)
+	if err != nil {
+		return err
+	}
+
+	km.keysLock.Lock()
+	added, removed := km.keys.diff(keys)
+	km.keys = keys
+	km.keysLock.Unlock()
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	for _, publicKey := range added {
+		km.log.WithField("public_key", publicKey).Info("discovered account")
+	}
+	for _, publicKey := range removed {
+		km.log.WithField("public_key", publicKey).Info("account removed")
+	}
+	km.keysFeed.Send(keys.sorted())
+
+	return nil
+}
+
+// watchKeys refreshes the discovered accounts periodically until the context is done.
+func (km *KeyManager) watchKeys(ctx context.Context) {
+	ticker := time.NewTicker(km.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Failures are logged, the known accounts are kept until the next refresh
+			_ = km.RefreshKeys()
+		}
+	}
+}
+
+// SubscribeAccountChanges subscribes the given channel to the public keys the key manager signs with,
+// sent every time discovered accounts are added or removed.
+func (km *KeyManager) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	return km.keysFeed.Subscribe(pubKeysChan)
+}
+
+// listAccounts returns the public keys of all accounts of the mount.
+func (km *KeyManager) listAccounts() ([]string, error) {
+	var ret []string
+	var cursor string
+	for {
+		query := url.Values{}
+		query.Set("list", "true")
+		query.Set("limit", strconv.Itoa(discoveryPageSize))
+		if len(cursor) > 0 {
+			query.Set("cursor", cursor)
+		}
+
+		var resp AccountsResponse
+		if err := km.sendRequest(http.MethodGet, backend.AccountsPattern+"?"+query.Encode(), nil, &resp); err != nil {
+			return nil, requestError(err, "failed to send list accounts request to remote vault wallet")
+		}
+
+		for _, account := range resp.Data.Accounts {
+			ret = append(ret, account.ValidationPubKey)
+		}
+
+		if len(resp.Data.NextCursor) == 0 {
+			return ret, nil
+		}
+		cursor = resp.Data.NextCursor
+	}
+}
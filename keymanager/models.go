@@ -1,5 +1,9 @@
 package keymanager
 
+import (
+	"github.com/bloxapp/key-vault/utils/errorex"
+)
+
 // SignAttestationRequest is the request body of vault sign attestation endpoint.
 type SignAttestationRequest struct {
 	PubKey          string `json:"public_key"`
@@ -40,3 +44,68 @@ type SignResponse struct {
 type SignatureModel struct {
 	Signature string `json:"signature"`
 }
+
+// AccountsResponse is the vault list accounts response model.
+type AccountsResponse struct {
+	Data AccountsModel `json:"data"`
+}
+
+// AccountsModel represents vault accounts model.
+type AccountsModel struct {
+	Accounts   []AccountModel `json:"accounts"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+// AccountModel represents vault account model.
+type AccountModel struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	ValidationPubKey string `json:"validationPubKey"`
+	WithdrawalPubKey string `json:"withdrawalPubKey"`
+}
+
+// AccountMetadataResponse is the vault account metadata response model.
+type AccountMetadataResponse struct {
+	Data AccountMetadataModel `json:"data"`
+}
+
+// AccountMetadataModel represents vault account metadata model.
+type AccountMetadataModel struct {
+	Labels       map[string]string `json:"labels"`
+	FeeRecipient string            `json:"fee_recipient"`
+	Graffiti     string            `json:"graffiti"`
+	Status       string            `json:"status"`
+	ImportedAt   int64             `json:"imported_at"`
+}
+
+// HealthResponse is the vault health response model.
+type HealthResponse struct {
+	Data HealthModel `json:"data"`
+}
+
+// HealthModel represents vault health model.
+type HealthModel struct {
+	Ready                bool     `json:"ready"`
+	Reasons              []string `json:"reasons"`
+	Network              string   `json:"network"`
+	Wallet               bool     `json:"wallet"`
+	Accounts             int      `json:"accounts"`
+	PausedAccounts       int      `json:"paused_accounts"`
+	Halted               bool     `json:"halted"`
+	SchemaVersion        int      `json:"schema_version"`
+	CurrentSchemaVersion int      `json:"current_schema_version"`
+	Storage              bool     `json:"storage"`
+	Version              string   `json:"version"`
+}
+
+// ErrorResponse is the vault error response model.
+type ErrorResponse struct {
+	Data ErrorModel `json:"data"`
+}
+
+// ErrorModel represents vault error model.
+type ErrorModel struct {
+	Code       errorex.ErrorCode `json:"code"`
+	Message    string            `json:"message"`
+	StatusCode int               `json:"status_code"`
+}
@@ -14,6 +14,18 @@ type Config struct {
 	AccessToken string `json:"access_token"`
 	PubKey      string `json:"public_key"`
 	Network     string `json:"network"`
+
+	// PubKeys are more accounts to sign with. The accounts of the mount are discovered if no public key is set.
+	PubKeys []string `json:"public_keys"`
+
+	// Auth configures how the Vault token is obtained, AccessToken is used as is if not set.
+	Auth *AuthConfig `json:"auth"`
+
+	// TLS configures the TLS connections to the remote vault, the default TLS settings are used if not set.
+	TLS *TLSConfig `json:"tls"`
+
+	// RefreshInterval is how often the discovered accounts are refreshed, as a duration string, one minute by default.
+	RefreshInterval string `json:"refresh_interval"`
 }
 
 // UnmarshalConfigFile attempts to JSON unmarshal a keymanager
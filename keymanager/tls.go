@@ -0,0 +1,204 @@
+package keymanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tlsHandshakeTimeout is the maximum time the TLS handshake of a connection may take.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// tlsVersions maps the supported minimum TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig configures the TLS connections to the remote vault.
+// The files are read again when they change on disk, new connections use the rotated certificates.
+type TLSConfig struct {
+	// CAFile is the PEM bundle of the CAs the server certificate is verified with, the system CAs if empty
+	CAFile string `json:"ca_file"`
+
+	// CertFile and KeyFile are the PEM client certificate and key presented for mutual TLS
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+
+	// MinVersion is the minimum TLS version, 1.2 or 1.3, 1.2 by default
+	MinVersion string `json:"min_version"`
+
+	// ServerName overrides the host name the server certificate is verified against
+	ServerName string `json:"server_name"`
+}
+
+// tlsLoader builds the TLS config of the connections from the configured files,
+// loading them again when they are modified.
+type tlsLoader struct {
+	config     *TLSConfig
+	minVersion uint16
+	log        *logrus.Entry
+
+	lock      sync.Mutex
+	modTimes  []time.Time
+	tlsConfig *tls.Config
+}
+
+// newTLSLoader is the constructor of tlsLoader, it fails if the files can't be loaded.
+func newTLSLoader(log *logrus.Entry, config *TLSConfig) (*tlsLoader, error) {
+	if (len(config.CertFile) == 0) != (len(config.KeyFile) == 0) {
+		return nil, NewGenericErrorMessage("tls requires both cert_file and key_file")
+	}
+
+	minVersion := uint16(tls.VersionTLS12)
+	if len(config.MinVersion) > 0 {
+		version, ok := tlsVersions[config.MinVersion]
+		if !ok {
+			return nil, NewGenericErrorMessage("unsupported tls min_version '%s'", config.MinVersion)
+		}
+		minVersion = version
+	}
+
+	loader := &tlsLoader{
+		config:     config,
+		minVersion: minVersion,
+		log:        log,
+	}
+
+	loader.modTimes = loader.stat()
+	tlsConfig, err := loader.load()
+	if err != nil {
+		return nil, err
+	}
+	loader.tlsConfig = tlsConfig
+
+	return loader, nil
+}
+
+// current returns the TLS config, loaded again if a file was modified.
+// The previous config is kept if the modified files can't be loaded, e.g. while they are being written.
+func (l *tlsLoader) current() *tls.Config {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	modTimes := l.stat()
+	if equalTimes(modTimes, l.modTimes) {
+		return l.tlsConfig
+	}
+
+	tlsConfig, err := l.load()
+	if err != nil {
+		l.log.WithError(err).Error("failed to reload TLS certificates, keeping the previous ones")
+		return l.tlsConfig
+	}
+
+	l.modTimes = modTimes
+	l.tlsConfig = tlsConfig
+	l.log.Info("reloaded TLS certificates")
+	return l.tlsConfig
+}
+
+// load reads the configured files.
+func (l *tlsLoader) load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: l.minVersion,
+		ServerName: l.config.ServerName,
+	}
+
+	if len(l.config.CAFile) > 0 {
+		ca, err := ioutil.ReadFile(l.config.CAFile)
+		if err != nil {
+			return nil, NewGenericError(err, "failed to read CA file")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, NewGenericErrorMessage("no certificate found in CA file %s", l.config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(l.config.CertFile) > 0 {
+		certificate, err := tls.LoadX509KeyPair(l.config.CertFile, l.config.KeyFile)
+		if err != nil {
+			return nil, NewGenericError(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// stat returns the modification times of the configured files, zero for the ones that can't be read.
+func (l *tlsLoader) stat() []time.Time {
+	files := []string{l.config.CAFile, l.config.CertFile, l.config.KeyFile}
+	ret := make([]time.Time, len(files))
+	for i, file := range files {
+		if len(file) == 0 {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			ret[i] = info.ModTime()
+		}
+	}
+	return ret
+}
+
+// DialTLS dials a TLS connection with the current TLS config.
+func (l *tlsLoader) DialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	tlsConfig := l.current().Clone()
+	if len(tlsConfig.ServerName) == 0 {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ServerName = host
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(tlsHandshakeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := tlsConn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// equalTimes returns true if both lists hold the same times.
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,23 +1,99 @@
 # Ability to list existing wallet accounts ("list")
-path "ethereum/test/accounts" {
+path "ethereum/+/accounts" {
   capabilities = ["list"]
 }
-path "ethereum/launchtest/accounts" {
-  capabilities = ["list"]
+
+# Ability to read account details ("read")
+path "ethereum/+/accounts/+" {
+  capabilities = ["read"]
 }
 
 # Ability to sign data ("create")
-path "ethereum/test/accounts/sign-*" {
+path "ethereum/+/accounts/sign-*" {
   capabilities = ["create"]
 }
-path "ethereum/launchtest/accounts/sign-*" {
-  capabilities = ["create"]
+
+# Ability to manage account metadata ("create", "update", "read")
+path "ethereum/+/accounts/+/metadata" {
+  capabilities = ["create", "update", "read"]
 }
 
 # Ability to update storage ("create")
-path "ethereum/test/storage" {
+path "ethereum/+/storage" {
   capabilities = ["create"]
 }
-path "ethereum/launchtest/storage" {
+
+# Ability to migrate storage ("create", "read")
+path "ethereum/+/storage/migration" {
+  capabilities = ["create", "read"]
+}
+
+# Ability to check and rebuild the account index ("create", "read")
+path "ethereum/+/storage/account-index" {
+  capabilities = ["create", "read"]
+}
+
+# Ability to read the config history ("read")
+path "ethereum/+/config/history" {
+  capabilities = ["read"]
+}
+
+# Ability to read the signing audit log ("read")
+path "ethereum/+/audit" {
+  capabilities = ["read"]
+}
+
+# Ability to read the metrics ("read")
+path "ethereum/+/metrics" {
+  capabilities = ["read"]
+}
+
+# Ability to read and reset the signing stats ("read", "delete")
+path "ethereum/+/stats" {
+  capabilities = ["read", "delete"]
+}
+path "ethereum/+/accounts/+/stats" {
+  capabilities = ["read", "delete"]
+}
+
+# Ability to manage the webhooks ("list", "create", "update", "read", "delete")
+path "ethereum/+/webhooks" {
+  capabilities = ["list"]
+}
+path "ethereum/+/webhooks/+" {
+  capabilities = ["create", "update", "read", "delete"]
+}
+
+# Ability to read the mount health ("read")
+path "ethereum/+/health" {
+  capabilities = ["read"]
+}
+
+# Ability to backup and restore ("create")
+path "ethereum/+/backup" {
+  capabilities = ["create"]
+}
+path "ethereum/+/restore" {
+  capabilities = ["create"]
+}
+
+# Ability to create wallet and accounts ("create")
+path "ethereum/+/wallet" {
+  capabilities = ["create"]
+}
+path "ethereum/+/wallet/accounts" {
+  capabilities = ["create"]
+}
+path "ethereum/+/wallet/accounts/import" {
+  capabilities = ["create"]
+}
+
+# Ability to generate deposit data ("create")
+path "ethereum/+/accounts/+/deposit-data" {
+  capabilities = ["create"]
+}
+
+# Ability to sign BLS to execution changes ("create")
+path "ethereum/+/accounts/+/bls-to-execution-change" {
   capabilities = ["create"]
 }
@@ -1,15 +1,24 @@
 # Ability to list existing wallet accounts ("list")
-path "ethereum/test/accounts" {
+path "ethereum/+/accounts" {
   capabilities = ["list"]
 }
-path "ethereum/launchtest/accounts" {
-  capabilities = ["list"]
+
+# Ability to read account details ("read")
+path "ethereum/+/accounts/+" {
+  capabilities = ["read"]
 }
 
 # Ability to sign data ("create")
-path "ethereum/test/accounts/sign-*" {
+path "ethereum/+/accounts/sign-*" {
   capabilities = ["create"]
 }
-path "ethereum/launchtest/accounts/sign-*" {
-  capabilities = ["create"]
+
+# Ability to read account metadata ("read")
+path "ethereum/+/accounts/+/metadata" {
+  capabilities = ["read"]
+}
+
+# Ability to read the mount health ("read")
+path "ethereum/+/health" {
+  capabilities = ["read"]
 }
@@ -1,8 +1,6 @@
 package errorex
 
 import (
-	"net/http"
-
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -25,10 +23,5 @@ func (e *ErrBadRequest) Error() string {
 
 // ToLogicalResponse converts error to logical response model
 func (e *ErrBadRequest) ToLogicalResponse() (*logical.Response, error) {
-	return logical.RespondWithStatusCode(&logical.Response{
-		Data: map[string]interface{}{
-			"message":     e.ErrorMsg,
-			"status_code": http.StatusBadRequest,
-		},
-	}, nil, http.StatusBadRequest)
+	return codedResponse(CodeBadRequest, e.ErrorMsg)
 }
@@ -0,0 +1,110 @@
+package errorex
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pkg/errors"
+)
+
+// ErrorCode is the stable code of an error returned by the plugin.
+type ErrorCode string
+
+// Error codes
+const (
+	// CodeSlashableDoubleVote is the code of an attestation refused as a double vote
+	CodeSlashableDoubleVote ErrorCode = "SLASHABLE_DOUBLE_VOTE"
+
+	// CodeSlashableSurround is the code of an attestation refused as a surrounding or surrounded vote
+	CodeSlashableSurround ErrorCode = "SLASHABLE_SURROUND"
+
+	// CodeDoubleProposal is the code of a proposal refused by slashing protection
+	CodeDoubleProposal ErrorCode = "DOUBLE_PROPOSAL"
+
+	// CodeLocked is the code of a message refused because the account is being signed with
+	CodeLocked ErrorCode = "LOCKED"
+
+	// CodeAccountNotFound is the code of a request about an unknown account
+	CodeAccountNotFound ErrorCode = "ACCOUNT_NOT_FOUND"
+
+	// CodeNotConfigured is the code of a request to a mount that has not been configured yet
+	CodeNotConfigured ErrorCode = "NOT_CONFIGURED"
+
+	// CodeBadRequest is the code of an invalid request
+	CodeBadRequest ErrorCode = "BAD_REQUEST"
+
+	// CodeHalted is the code of a message refused because signing is paused
+	CodeHalted ErrorCode = "HALTED"
+)
+
+// statusCodes maps the error codes to HTTP statuses.
+var statusCodes = map[ErrorCode]int{
+	CodeSlashableDoubleVote: http.StatusPreconditionFailed,
+	CodeSlashableSurround:   http.StatusPreconditionFailed,
+	CodeDoubleProposal:      http.StatusPreconditionFailed,
+	CodeLocked:              http.StatusLocked,
+	CodeAccountNotFound:     http.StatusNotFound,
+	CodeNotConfigured:       http.StatusServiceUnavailable,
+	CodeBadRequest:          http.StatusBadRequest,
+	CodeHalted:              http.StatusForbidden,
+}
+
+// StatusCode returns the HTTP status of the error code, 500 for unknown codes.
+func (code ErrorCode) StatusCode() int {
+	if status, ok := statusCodes[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Slashable returns true if the code is a refusal of slashing protection.
+func (code ErrorCode) Slashable() bool {
+	return code == CodeSlashableDoubleVote || code == CodeSlashableSurround || code == CodeDoubleProposal
+}
+
+// CodedError is an error with a stable code.
+type CodedError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// NewCodedError is the constructor of CodedError.
+func NewCodedError(code ErrorCode, message string) *CodedError {
+	return &CodedError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error implements error interface
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+// ToLogicalResponse converts error to logical response model
+func (e *CodedError) ToLogicalResponse() (*logical.Response, error) {
+	return codedResponse(e.Code, e.Message)
+}
+
+// CodeOf returns the code of the given error, possibly wrapped. Returns an empty code if the error has none.
+func CodeOf(err error) ErrorCode {
+	switch e := errors.Cause(err).(type) {
+	case *CodedError:
+		return e.Code
+	case *ErrBadRequest:
+		return CodeBadRequest
+	default:
+		return ""
+	}
+}
+
+// codedResponse returns the response of an error with the given code and message.
+func codedResponse(code ErrorCode, message string) (*logical.Response, error) {
+	return logical.RespondWithStatusCode(&logical.Response{
+		Data: map[string]interface{}{
+			"message":     message,
+			"status_code": code.StatusCode(),
+			"code":        code,
+		},
+	}, nil, code.StatusCode())
+}
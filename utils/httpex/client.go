@@ -1,6 +1,8 @@
 package httpex
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -15,15 +17,45 @@ const (
 	clientTimeout   = time.Minute
 )
 
+// DialTLSFunc dials the TLS connections of a client.
+type DialTLSFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
 // CreateClient creates a new HTTP client.
-func CreateClient() *http.Client {
+// TLS connections are dialed with the given function, with the default TLS settings if nil.
+func CreateClient(dialTLS DialTLSFunc) *http.Client {
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = attempts
 	retryClient.RetryWaitMin = attemptsWaitMin
 	retryClient.RetryWaitMax = attemptsWaitMax
+	if dialTLS != nil {
+		retryClient.HTTPClient.Transport = createTransport(dialTLS)
+	}
+
+	// The last response is returned once the retries are exhausted, so its error can be read
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
 
 	client := retryClient.StandardClient()
 	client.Timeout = clientTimeout
 
 	return client
 }
+
+// CreateProbeClient creates a new HTTP client that doesn't retry.
+// It is meant for health checks, which report an unavailable service with its status code.
+func CreateProbeClient(dialTLS DialTLSFunc) *http.Client {
+	client := &http.Client{
+		Timeout: clientTimeout,
+	}
+	if dialTLS != nil {
+		client.Transport = createTransport(dialTLS)
+	}
+
+	return client
+}
+
+// createTransport returns the default transport with TLS connections dialed with the given function.
+func createTransport(dialTLS DialTLSFunc) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = dialTLS
+	return transport
+}